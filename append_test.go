@@ -0,0 +1,95 @@
+package hpack_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type appendVec struct {
+	X, Y float32
+}
+
+type appendPacket struct {
+	ID     uint32        `msgpack:"id"`
+	Seq    int64         `msgpack:"seq"`
+	Name   string        `msgpack:"name"`
+	Pos    appendVec     `msgpack:"pos"`
+	Vel    *appendVec    `msgpack:"vel,omitempty"`
+	Tags   []string      `msgpack:"tags,omitempty"`
+	HP     int16         `msgpack:"hp"`
+	Alive  bool          `msgpack:"alive"`
+	At     time.Time     `msgpack:"at"`
+	Data   []byte        `msgpack:"data"`
+	Items  []appendVec   `msgpack:"items"`
+	Wide   int           `msgpack:"wide,hash=0x12345678"`
+	Nested *appendPacket `msgpack:"nested,omitempty"`
+}
+
+func newAppendPacket() *appendPacket {
+	return &appendPacket{
+		ID: 70000, Seq: -5, Name: "player-one", Pos: appendVec{1, 2}, Vel: &appendVec{3, 4}, HP: 300, Alive: true,
+		At: time.Unix(1700000000, 5), Data: []byte{1, 2, 3}, Items: []appendVec{{1, 1}, {2, 2}}, Wide: 9,
+		Nested: &appendPacket{ID: 1, Name: "child", Tags: []string{"a"}},
+	}
+}
+
+func TestAppendMarshal(t *testing.T) {
+	p := newAppendPacket()
+	want, err := hpack.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := hpack.AppendMarshal([]byte("pre"), p)
+	if err != nil || string(got[:3]) != "pre" || !bytes.Equal(got[3:], want) {
+		t.Fatalf("got % x, err %v, want % x", got, err, want)
+	}
+
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	got, err = enc.AppendEncode(nil, p)
+	if err != nil || !bytes.Equal(got, want) || buf.Len() != 0 {
+		t.Fatalf("AppendEncode = % x, err %v, writer %d bytes", got, err, buf.Len())
+	}
+	// AppendEncode 이후에도 Encoder의 writer를 그대로 사용한다.
+	if err := enc.Encode(p); err != nil || !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Encode after AppendEncode = % x, err %v", buf.Bytes(), err)
+	}
+
+	// 에러이면 dst를 그대로 반환한다.
+	dst := []byte("pre")
+	if got, err := hpack.AppendMarshal(dst, &struct{ C chan int }{C: make(chan int)}); err == nil || !bytes.Equal(got, dst) {
+		t.Fatalf("got % x, err %v", got, err)
+	}
+}
+
+func TestAppendMarshalAllocs(t *testing.T) {
+	p := newAppendPacket()
+	dst := make([]byte, 0, 1024)
+	if _, err := hpack.AppendMarshal(dst, p); err != nil { // 필드 캐시 준비
+		t.Fatal(err)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := hpack.AppendMarshal(dst[:0], p); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("AppendMarshal: %v allocs, want 0", allocs)
+	}
+
+	enc := hpack.NewEncoder(nil)
+	enc.SetSortMapKeys(true)
+	allocs = testing.AllocsPerRun(100, func() {
+		if _, err := enc.AppendEncode(dst[:0], p); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("AppendEncode: %v allocs, want 0", allocs)
+	}
+}
@@ -0,0 +1,72 @@
+// Command hpack-schema : hpack 스키마 기술자(JSON) 도구
+//
+//	hpack-schema diff old.json new.json
+//
+// 두 스키마를 비교하여 변경 사항을 출력하고, 와이어 호환성이 깨지는 변경이 있으면 1로 종료한다.
+// 스키마 파일을 읽을 수 없거나 잘못된 경우(Root, Ref가 가리키는 타입이 없는 경우 등) 2로 종료한다.
+// CI에서 라이브 클라이언트에 배포된 스키마와 새 빌드의 스키마를 비교하는 용도.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/boldplaygames/hpack"
+)
+
+const usage = "usage: hpack-schema diff old.json new.json"
+
+func main() {
+	if len(os.Args) != 4 || os.Args[1] != "diff" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	breaking, err := diff(os.Args[2], os.Args[3])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if breaking {
+		os.Exit(1)
+	}
+}
+
+func diff(oldPath, newPath string) (breaking bool, err error) {
+	oldSchema, err := readSchema(oldPath)
+	if err != nil {
+		return false, err
+	}
+	newSchema, err := readSchema(newPath)
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := hpack.DiffSchema(oldSchema, newSchema)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range changes {
+		if c.Breaking() {
+			breaking = true
+			fmt.Println("BREAKING", c)
+		} else {
+			fmt.Println("ok      ", c)
+		}
+	}
+	return breaking, nil
+}
+
+func readSchema(path string) (*hpack.Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema hpack.Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &schema, nil
+}
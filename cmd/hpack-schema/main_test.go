@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type player struct {
+	ID   int    `msgpack:"id"`
+	Name string `msgpack:"name"`
+}
+
+type playerRemoved struct {
+	ID int `msgpack:"id"`
+}
+
+func writeSchema(t *testing.T, v interface{}, modify func(*hpack.Schema)) string {
+	t.Helper()
+	s, err := hpack.SchemaOf(reflect.TypeOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modify != nil {
+		modify(s)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		new      string
+		breaking bool
+		wantErr  bool
+	}{
+		{"same", writeSchema(t, player{}, nil), false, false},
+		{"removed", writeSchema(t, playerRemoved{}, nil), true, false},
+		{"missing root", writeSchema(t, player{}, func(s *hpack.Schema) { s.Root = "missing" }), false, true},
+		{"missing file", filepath.Join(t.TempDir(), "none.json"), false, true},
+	}
+	old := writeSchema(t, player{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaking, err := diff(old, tt.new)
+			if (err != nil) != tt.wantErr || breaking != tt.breaking {
+				t.Fatalf("breaking = %v, err %v", breaking, err)
+			}
+		})
+	}
+}
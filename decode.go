@@ -24,6 +24,9 @@ const (
 	disallowUnknownFieldsFlag
 	usePreallocateValues
 	disableAllocLimitFlag
+	_ // useInternedStringsFlag (encode.go의 플래그를 Decoder에서도 사용)
+	msgpackCompatFlag
+	strictFlag
 )
 
 type bufReader interface {
@@ -64,16 +67,48 @@ func Unmarshal(data []byte, v interface{}) error {
 	return err
 }
 
+// UnmarshalStrict is like Unmarshal, but decodes with UseStrict and
+// returns an error when data has bytes left after the value.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	dec := GetDecoder()
+	r := bytes.NewReader(data)
+	dec.Reset(r)
+	dec.UsePreallocateValues(true)
+	dec.UseStrict(true)
+	err := dec.Decode(v)
+	if err == nil && r.Len() > 0 {
+		err = newError(strictErrorf("%d trailing bytes after the value", r.Len()), dec.read)
+	}
+
+	PutDecoder(dec)
+
+	return err
+}
+
 // // A Decoder reads and decodes MessagePack values from an input stream.
 type Decoder struct {
 	r          io.Reader
 	s          io.ByteScanner
 	mapDecoder func(*Decoder) (interface{}, error)
-	structTag  string
+	tags       structTags
+	hasher     FieldHasher
 	buf        []byte
 	rec        []byte
 	dict       []string
 	flags      uint32
+
+	// DecodeInterface가 hpack struct 여부를 확인하느라 먼저 읽은 map 길이. DecodeMapLen이 한 번 반환
+	peekedMapLen    int
+	hasPeekedMapLen bool
+
+	logger         Logger
+	onUnknownField UnknownFieldFunc
+
+	limits    DecodeLimits
+	depth     int   // 현재 array, map, struct 중첩 깊이
+	read      int64 // ResetReader 이후 읽은 바이트 수
+	limitBase int64 // SetLimits 호출 시점의 read
+	alloc     int64 // SetLimits 이후 길이 헤더로 할당한 크기
 }
 
 // NewDecoder returns a new decoder that reads from r.
@@ -97,7 +132,11 @@ func (d *Decoder) Reset(r io.Reader) {
 func (d *Decoder) ResetDict(r io.Reader, dict []string) {
 	d.ResetReader(r)
 	d.flags = 0
-	d.structTag = ""
+	d.logger = nil
+	d.onUnknownField = nil
+	d.limits = DecodeLimits{}
+	d.tags = structTags{}
+	d.hasher = nil
 	d.dict = dict
 }
 
@@ -112,6 +151,11 @@ func (d *Decoder) WithDict(dict []string, fn func(*Decoder) error) error {
 func (d *Decoder) ResetReader(r io.Reader) {
 	d.mapDecoder = nil
 	d.dict = nil
+	d.hasPeekedMapLen = false
+	d.depth = 0
+	d.read = 0
+	d.limitBase = 0
+	d.alloc = 0
 
 	if br, ok := r.(bufReader); ok {
 		d.r = br
@@ -143,7 +187,31 @@ func (d *Decoder) UseLooseInterfaceDecoding(on bool) {
 // SetCustomStructTag causes the decoder to use the supplied tag as a fallback option
 // if there is no msgpack tag.
 func (d *Decoder) SetCustomStructTag(tag string) {
-	d.structTag = tag
+	d.tags.fallback = tag
+}
+
+// SetStructTags causes the decoder to read field names and options from the primary tag
+// (msgpack if empty), and from the fallback tag when the primary tag is missing.
+// e.g. SetStructTags("hpack", "json")
+func (d *Decoder) SetStructTags(primary, fallback string) {
+	d.tags = structTags{primary: primary, fallback: fallback}
+}
+
+// SetFieldHasher causes the Decoder to hash struct field names with hasher.
+// Types registered with RegisterFieldHasher keep their own hasher. nil restores the default (CRC32-IEEE).
+func (d *Decoder) SetFieldHasher(hasher FieldHasher) {
+	d.hasher = hasher
+}
+
+// UseMsgpackCompat causes the Decoder to accept standard msgpack maps with string keys
+// (without the field name size flag) when decoding into structs.
+// String keys are hashed with the struct's FieldHasher and matched against its fields.
+func (d *Decoder) UseMsgpackCompat(on bool) {
+	if on {
+		d.flags |= msgpackCompatFlag
+	} else {
+		d.flags &= ^msgpackCompatFlag
+	}
 }
 
 // DisallowUnknownFields causes the Decoder to return an error when the destination
@@ -158,13 +226,15 @@ func (d *Decoder) DisallowUnknownFields(on bool) {
 }
 
 // UseInternedStrings enables support for decoding interned strings.
-// func (d *Decoder) UseInternedStrings(on bool) {
-// 	if on {
-// 		d.flags |= useInternedStringsFlag
-// 	} else {
-// 		d.flags &= ^useInternedStringsFlag
-// 	}
-// }
+// Every decoded string of minInternedStringLen bytes or more is added to the dictionary,
+// mirroring Encoder.UseInternedStrings.
+func (d *Decoder) UseInternedStrings(on bool) {
+	if on {
+		d.flags |= useInternedStringsFlag
+	} else {
+		d.flags &= ^useInternedStringsFlag
+	}
+}
 
 // UsePreallocateValues enables preallocating values in chunks
 func (d *Decoder) UsePreallocateValues(on bool) {
@@ -305,10 +375,18 @@ func (d *Decoder) Decode(v interface{}) error {
 			if vv.Kind() != reflect.Pointer {
 				return fmt.Errorf("hpack: Decode(non-pointer %s)", vv.Type().String())
 			}
+			// interface에서 꺼낸 포인터는 settable이 아니므로 가리키는 값에 디코딩
+			if vv.IsNil() {
+				return fmt.Errorf("hpack: Decode(non-settable %s)", vv.Type().String())
+			}
+			vv = vv.Elem()
 		}
 	}
 
-	return d.DecodeValue(vv)
+	if err := d.DecodeValue(vv); err != nil {
+		return d.valueError(err, vv.Type())
+	}
+	return nil
 }
 
 func (d *Decoder) DecodeMulti(v ...interface{}) error {
@@ -338,20 +416,22 @@ func (d *Decoder) DecodeNil() error {
 		return err
 	}
 	if c != msgpcode.Nil {
-		return fmt.Errorf("hpack: invalid code=%x decoding nil", c)
+		return unexpectedCodeError{code: c, hint: "nil"}
 	}
 	return nil
 }
 
 func (d *Decoder) decodeNilValue(v reflect.Value) error {
 	err := d.DecodeNil()
-	if v.IsNil() {
+	if nilable(v.Kind()) && v.IsNil() {
 		return err
 	}
 	if v.Kind() == reflect.Pointer {
 		v = v.Elem()
 	}
-	v.Set(reflect.Zero(v.Type()))
+	if v.CanSet() {
+		v.Set(reflect.Zero(v.Type()))
+	}
 	return err
 }
 
@@ -360,6 +440,9 @@ func (d *Decoder) DecodeBool() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if c == msgpcode.Nil && d.strict() {
+		return false, errStrictNil("bool")
+	}
 	return d.bool(c)
 }
 
@@ -373,7 +456,7 @@ func (d *Decoder) bool(c byte) (bool, error) {
 	if c == msgpcode.True {
 		return true, nil
 	}
-	return false, fmt.Errorf("hpack: invalid code=%x decoding bool", c)
+	return false, unexpectedCodeError{code: c, hint: "bool"}
 }
 
 func (d *Decoder) DecodeDuration() (time.Duration, error) {
@@ -408,17 +491,13 @@ func (d *Decoder) DecodeInterface() (interface{}, error) {
 		return int8(c), nil
 	}
 	if msgpcode.IsFixedMap(c) {
-		err = d.s.UnreadByte()
-		if err != nil {
-			return nil, err
-		}
-		return d.decodeMapDefault()
+		return d.decodeMapInterface(c)
 	}
 	if msgpcode.IsFixedArray(c) {
 		return d.decodeSlice(c)
 	}
 	if msgpcode.IsFixedString(c) {
-		return d.string(c)
+		return d.stringCond(c)
 	}
 
 	switch c {
@@ -430,40 +509,23 @@ func (d *Decoder) DecodeInterface() (interface{}, error) {
 		return d.float32(c)
 	case msgpcode.Double:
 		return d.float64(c)
-	case msgpcode.Uint8:
-		return d.uint8()
-	case msgpcode.Uint16:
-		return d.uint16()
-	case msgpcode.Uint32:
-		return d.uint32()
-	case msgpcode.Uint64:
-		return d.uint64()
-	case msgpcode.Int8:
-		return d.int8()
-	case msgpcode.Int16:
-		return d.int16()
-	case msgpcode.Int32:
-		return d.int32()
-	case msgpcode.Int64:
-		return d.int64()
+	case msgpcode.Uint8, msgpcode.Uint16, msgpcode.Uint32, msgpcode.Uint64,
+		msgpcode.Int8, msgpcode.Int16, msgpcode.Int32, msgpcode.Int64:
+		return d.intInterface(c)
 	case msgpcode.Bin8, msgpcode.Bin16, msgpcode.Bin32:
 		return d.bytes(c, nil)
 	case msgpcode.Str8, msgpcode.Str16, msgpcode.Str32:
-		return d.string(c)
+		return d.stringCond(c)
 	case msgpcode.Array16, msgpcode.Array32:
 		return d.decodeSlice(c)
 	case msgpcode.Map16, msgpcode.Map32:
-		err = d.s.UnreadByte()
-		if err != nil {
-			return nil, err
-		}
-		return d.decodeMapDefault()
-		// case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
-		// 	msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
-		// 	return d.decodeInterfaceExt(c)
+		return d.decodeMapInterface(c)
+	case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
+		msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
+		return d.decodeInterfaceExt(c)
 	}
 
-	return 0, fmt.Errorf("hpack: unknown code %x decoding interface{}", c)
+	return 0, unexpectedCodeError{code: c, hint: "interface{}"}
 }
 
 // DecodeInterfaceLoose is like DecodeInterface except that:
@@ -521,7 +583,7 @@ func (d *Decoder) DecodeInterface() (interface{}, error) {
 // 		return d.decodeInterfaceExt(c)
 // 	}
 
-// 	return 0, fmt.Errorf("hpack: unknown code %x decoding interface{}", c)
+// 	return 0, unexpectedCodeError{code: c, hint: "interface{}"}
 // }
 
 // Skip skips next value.
@@ -541,7 +603,7 @@ func (d *Decoder) Skip() error {
 		return d.skipSlice(c)
 	}
 	if msgpcode.IsFixedString(c) {
-		return d.skipBytes(c)
+		return d.skipString(c)
 	}
 
 	switch c {
@@ -558,17 +620,17 @@ func (d *Decoder) Skip() error {
 	case msgpcode.Bin8, msgpcode.Bin16, msgpcode.Bin32:
 		return d.skipBytes(c)
 	case msgpcode.Str8, msgpcode.Str16, msgpcode.Str32:
-		return d.skipBytes(c)
+		return d.skipString(c)
 	case msgpcode.Array16, msgpcode.Array32:
 		return d.skipSlice(c)
 	case msgpcode.Map16, msgpcode.Map32:
 		return d.skipMap(c)
-		// case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
-		// 	msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
-		// 	return d.skipExt(c)
+	case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
+		msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
+		return d.skipExt(c)
 	}
 
-	return fmt.Errorf("hpack: unknown code %x", c)
+	return unexpectedCodeError{code: c, hint: "value"}
 }
 
 func (d *Decoder) DecodeRaw() (RawMessage, error) {
@@ -581,6 +643,26 @@ func (d *Decoder) DecodeRaw() (RawMessage, error) {
 	return msg, nil
 }
 
+// skipRaw : DecodeRaw와 같지만 이미 기록 중이면(DecodeRaw, Unmarshaler) 읽은 바이트를 바깥 기록에도 이어 붙인다.
+func (d *Decoder) skipRaw() (RawMessage, error) {
+	outer := d.rec
+	d.rec = make([]byte, 0, 16)
+	err := d.Skip()
+	msg := RawMessage(d.rec)
+	if outer != nil {
+		d.rec = append(outer, msg...)
+	} else {
+		d.rec = nil
+	}
+	return msg, err
+}
+
+// InputOffset returns the number of input bytes consumed since the last Reset.
+// Bytes buffered by the Decoder but not yet decoded, and bytes read back by PeekCode, are not counted.
+func (d *Decoder) InputOffset() int64 {
+	return d.read
+}
+
 // PeekCode returns the next MessagePack code without advancing the reader.
 // Subpackage msgpack/codes defines the list of available msgpcode.
 func (d *Decoder) PeekCode() (byte, error) {
@@ -593,8 +675,7 @@ func (d *Decoder) PeekCode() (byte, error) {
 
 // ReadFull reads exactly len(buf) bytes into the buf.
 func (d *Decoder) ReadFull(buf []byte) error {
-	_, err := readN(d.r, buf, len(buf))
-	return err
+	return d.readFull(buf)
 }
 
 func (d *Decoder) hasNilCode() bool {
@@ -603,10 +684,14 @@ func (d *Decoder) hasNilCode() bool {
 }
 
 func (d *Decoder) readCode() (byte, error) {
+	if err := d.checkRead(1); err != nil {
+		return 0, err
+	}
 	c, err := d.s.ReadByte()
 	if err != nil {
 		return 0, err
 	}
+	d.read++
 	if d.rec != nil {
 		d.rec = append(d.rec, c)
 	}
@@ -614,7 +699,11 @@ func (d *Decoder) readCode() (byte, error) {
 }
 
 func (d *Decoder) readFull(b []byte) error {
-	_, err := io.ReadFull(d.r, b)
+	if err := d.checkRead(len(b)); err != nil {
+		return err
+	}
+	n, err := io.ReadFull(d.r, b)
+	d.read += int64(n)
 	if err != nil {
 		return err
 	}
@@ -625,20 +714,49 @@ func (d *Decoder) readFull(b []byte) error {
 }
 
 func (d *Decoder) readN(n int) ([]byte, error) {
+	var err error
+	d.buf, err = d.readBytes(d.buf, n)
+	if err != nil {
+		return nil, err
+	}
+	return d.buf, nil
+}
+
+// readBytes : b를 재사용하여 n 바이트를 읽음. 읽은 바이트 수를 세고 DecodeRaw를 위해 기록한다.
+// 끝까지 읽지 못하면 읽은 바이트 수에 더하지 않는다.
+func (d *Decoder) readBytes(b []byte, n int) ([]byte, error) {
+	if err := d.checkRead(n); err != nil {
+		return nil, err
+	}
+
 	var err error
 	if d.flags&disableAllocLimitFlag != 0 {
-		d.buf, err = readN(d.r, d.buf, n)
+		b, err = readN(d.r, b, n)
 	} else {
-		d.buf, err = readNGrow(d.r, d.buf, n)
+		b, err = readNGrow(d.r, b, n)
 	}
 	if err != nil {
 		return nil, err
 	}
+	d.read += int64(n)
 	if d.rec != nil {
 		// TODO: read directly into d.rec?
-		d.rec = append(d.rec, d.buf...)
+		d.rec = append(d.rec, b...)
 	}
-	return d.buf, nil
+	return b, nil
+}
+
+// allocCap : 길이 n인 컨테이너에 미리 할당할 원소 수
+// DisableAllocLimit이 아니면 limit을 넘지 않고, 원소마다 최소 1바이트가 필요하므로 남은 입력 길이(Len을 제공하는 reader)도 넘지 않는다.
+func (d *Decoder) allocCap(n, limit int) int {
+	if d.flags&disableAllocLimitFlag != 0 {
+		return n
+	}
+	n = min(n, limit)
+	if r, ok := d.r.(interface{ Len() int }); ok {
+		n = min(n, r.Len())
+	}
+	return n
 }
 
 func readN(r io.Reader, b []byte, n int) ([]byte, error) {
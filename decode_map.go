@@ -3,12 +3,13 @@ package hpack
 import (
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/vmihailenco/msgpack/v5/msgpcode"
 )
 
-var errArrayStruct = errors.New("hpack: number of fields in array-encoded struct has changed")
+var errArrayStruct = errors.New("hpack: array-encoded struct has more values than fields")
 
 var (
 	mapStringStringPtrType = reflect.TypeOf((*map[string]string)(nil))
@@ -33,12 +34,12 @@ func decodeMapValue(d *Decoder, v reflect.Value) error {
 		v.Set(reflect.Zero(typ))
 		return nil
 	}
+	if err := d.allocLen(n, mapEntrySize(typ)); err != nil {
+		return err
+	}
 
 	if v.IsNil() {
-		ln := n
-		if d.flags&disableAllocLimitFlag == 0 {
-			ln = min(ln, maxMapSize)
-		}
+		ln := d.allocCap(n, maxMapSize)
 		v.Set(reflect.MakeMapWithSize(typ, ln))
 	}
 	if n == 0 {
@@ -57,6 +58,11 @@ func (d *Decoder) decodeMapDefault() (interface{}, error) {
 
 // DecodeMapLen decodes map length. Length is -1 when map is nil.
 func (d *Decoder) DecodeMapLen() (int, error) {
+	if d.hasPeekedMapLen {
+		d.hasPeekedMapLen = false
+		return d.peekedMapLen, nil
+	}
+
 	c, err := d.readCode()
 	if err != nil {
 		return 0, err
@@ -80,17 +86,30 @@ func (d *Decoder) mapLen(c byte) (int, error) {
 		return -1, nil
 	}
 	if c >= msgpcode.FixedMapLow && c <= msgpcode.FixedMapHigh {
-		return int(c & msgpcode.FixedMapMask), nil
+		n := int(c & msgpcode.FixedMapMask)
+		return n, d.checkMapLen(n)
 	}
-	if c == msgpcode.Map16 {
+	var n int
+	switch c {
+	case msgpcode.Map16:
 		size, err := d.uint16()
-		return int(size), err
-	}
-	if c == msgpcode.Map32 {
+		if err != nil {
+			return 0, err
+		}
+		n = int(size)
+	case msgpcode.Map32:
 		size, err := d.uint32()
-		return int(size), err
+		if err != nil {
+			return 0, err
+		}
+		n = int(size)
+	default:
+		return 0, unexpectedCodeError{code: c, hint: "map length"}
+	}
+	if err := d.checkShortestLen(c, n); err != nil {
+		return 0, err
 	}
-	return 0, unexpectedCodeError{code: c, hint: "map length"}
+	return n, d.checkMapLen(n)
 }
 
 func decodeMapStringStringValue(d *Decoder, v reflect.Value) error {
@@ -107,22 +126,26 @@ func (d *Decoder) decodeMapStringStringPtr(ptr *map[string]string) error {
 		*ptr = nil
 		return nil
 	}
+	if err := d.allocLen(size, mapEntrySize(mapStringStringType)); err != nil {
+		return err
+	}
 
 	m := *ptr
 	if m == nil {
-		ln := size
-		if d.flags&disableAllocLimitFlag == 0 {
-			ln = min(size, maxMapSize)
-		}
+		ln := d.allocCap(size, maxMapSize)
 		*ptr = make(map[string]string, ln)
 		m = *ptr
 	}
 
+	seen := newKeySet[string](d)
 	for i := 0; i < size; i++ {
 		mk, err := d.DecodeString()
 		if err != nil {
 			return err
 		}
+		if !seen.add(mk) {
+			return errDuplicateMapKey(mk)
+		}
 		mv, err := d.DecodeString()
 		if err != nil {
 			return err
@@ -156,17 +179,32 @@ func (d *Decoder) DecodeMap() (map[string]interface{}, error) {
 	if n == -1 {
 		return nil, nil
 	}
+	return d.decodeMapN(n)
+}
+
+func (d *Decoder) decodeMapN(n int) (map[string]interface{}, error) {
+	if err := d.allocLen(n, mapEntrySize(mapStringInterfaceType)); err != nil {
+		return nil, err
+	}
+	if err := d.enterDepth(); err != nil {
+		return nil, err
+	}
+	defer d.leaveDepth()
 
-	m := make(map[string]interface{}, n)
+	m := make(map[string]interface{}, d.allocCap(n, maxMapSize))
+	seen := newKeySet[string](d)
 
 	for i := 0; i < n; i++ {
 		mk, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
+		if !seen.add(mk) {
+			return nil, errDuplicateMapKey(mk)
+		}
 		mv, err := d.decodeInterfaceCond()
 		if err != nil {
-			return nil, err
+			return nil, d.elemError(err, keySegment(reflect.ValueOf(mk)))
 		}
 		m[mk] = mv
 	}
@@ -183,14 +221,29 @@ func (d *Decoder) DecodeUntypedMap() (map[interface{}]interface{}, error) {
 	if n == -1 {
 		return nil, nil
 	}
+	if err := d.allocLen(n, 2*interfaceType.Size()); err != nil {
+		return nil, err
+	}
+	if err := d.enterDepth(); err != nil {
+		return nil, err
+	}
+	defer d.leaveDepth()
 
-	m := make(map[interface{}]interface{}, n)
+	m := make(map[interface{}]interface{}, d.allocCap(n, maxMapSize))
 
 	for i := 0; i < n; i++ {
 		mk, err := d.decodeInterfaceCond()
 		if err != nil {
 			return nil, err
 		}
+		if mk != nil && !reflect.TypeOf(mk).Comparable() {
+			return nil, fmt.Errorf("hpack: unsupported map key: %T", mk)
+		}
+		if d.strict() {
+			if _, ok := m[mk]; ok {
+				return nil, errDuplicateMapKey(mk)
+			}
+		}
 
 		mv, err := d.decodeInterfaceCond()
 		if err != nil {
@@ -227,24 +280,29 @@ func (d *Decoder) DecodeTypedMap() (interface{}, error) {
 	keyType := reflect.TypeOf(key)
 	valueType := reflect.TypeOf(value)
 
+	if keyType == nil || valueType == nil {
+		return nil, errors.New("hpack: DecodeTypedMap: nil key or value")
+	}
 	if !keyType.Comparable() {
 		return nil, fmt.Errorf("hpack: unsupported map key: %s", keyType.String())
 	}
 
 	mapType := reflect.MapOf(keyType, valueType)
-
-	ln := n
-	if d.flags&disableAllocLimitFlag == 0 {
-		ln = min(ln, maxMapSize)
+	if err := d.allocLen(n, mapEntrySize(mapType)); err != nil {
+		return nil, err
 	}
 
+	ln := d.allocCap(n, maxMapSize)
+
 	mapValue := reflect.MakeMapWithSize(mapType, ln)
 	mapValue.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(value))
 
-	n--
-	if err := d.decodeTypedMapValue(mapValue, n); err != nil {
+	if err := d.decodeTypedMapValue(mapValue, n-1); err != nil {
 		return nil, err
 	}
+	if d.strict() && mapValue.Len() != n {
+		return nil, errDuplicateMapKey(key)
+	}
 
 	return mapValue.Interface(), nil
 }
@@ -255,15 +313,27 @@ func (d *Decoder) decodeTypedMapValue(v reflect.Value, n int) error {
 		keyType   = typ.Key()
 		valueType = typ.Elem()
 	)
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
+
+	seen := newKeySet[interface{}](d)
 	for i := 0; i < n; i++ {
 		mk := d.newValue(keyType).Elem()
 		if err := d.DecodeValue(mk); err != nil {
 			return err
 		}
+		if !mk.Comparable() {
+			return fmt.Errorf("hpack: unsupported map key: %s", keyType)
+		}
+		if !seen.add(mk.Interface()) {
+			return errDuplicateMapKey(mk)
+		}
 
 		mv := d.newValue(valueType).Elem()
 		if err := d.DecodeValue(mv); err != nil {
-			return err
+			return d.elemError(err, keySegment(mk))
 		}
 
 		v.SetMapIndex(mk, mv)
@@ -277,6 +347,11 @@ func (d *Decoder) skipMap(c byte) error {
 	if err != nil {
 		return err
 	}
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
+
 	for i := 0; i < n; i++ {
 		if err := d.Skip(); err != nil {
 			return err
@@ -288,6 +363,71 @@ func (d *Decoder) skipMap(c byte) error {
 	return nil
 }
 
+// decodeMapInterface : DecodeInterface에서 map 코드를 만났을 때
+// 첫 키가 문자열(intern된 문자열 포함)이 아니면 map[interface{}]interface{}로 디코딩
+func (d *Decoder) decodeMapInterface(c byte) (interface{}, error) {
+	n, err := d.mapLen(c)
+	if err != nil {
+		return nil, err
+	}
+
+	untyped := false
+	if n > 0 && d.mapDecoder == nil {
+		next, err := d.PeekCode()
+		if err != nil {
+			return nil, err
+		}
+		untyped = !msgpcode.IsString(next) && !msgpcode.IsExt(next)
+	}
+
+	d.peekedMapLen = n
+	d.hasPeekedMapLen = true
+	var v interface{}
+	if untyped {
+		v, err = d.DecodeUntypedMap()
+	} else {
+		v, err = d.decodeMapDefault()
+	}
+	d.hasPeekedMapLen = false
+	return v, err
+}
+
+func (d *Decoder) decodeStructInterface(n int, fieldLen FieldNameSizeFlag) (map[uint32]interface{}, error) {
+	var widths []byte
+	if fieldLen == FieldNameSizeFlagMixed {
+		var err error
+		if widths, err = d.decodeMixedWidths(n); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.allocLen(n, 4+interfaceType.Size()); err != nil {
+		return nil, err
+	}
+	if err := d.enterDepth(); err != nil {
+		return nil, err
+	}
+	defer d.leaveDepth()
+
+	m := make(map[uint32]interface{}, d.allocCap(n, maxMapSize))
+	seen := newKeySet[uint32](d)
+	for i := range n {
+		fname, err := d.decodeFieldName(fieldWidth(fieldLen, widths, i))
+		if err != nil {
+			return nil, err
+		}
+		if !seen.add(fname.hash32) {
+			return nil, newError(strictErrorf("duplicate field"), d.read).withField(fname)
+		}
+		v, err := d.decodeInterfaceCond()
+		if err != nil {
+			return nil, err
+		}
+		m[fname.hash32] = v
+	}
+	return m, nil
+}
+
 func (d *Decoder) decodeFieldLen() (FieldNameSizeFlag, error) {
 	c, err := d.readCode()
 	if err != nil {
@@ -296,22 +436,101 @@ func (d *Decoder) decodeFieldLen() (FieldNameSizeFlag, error) {
 
 	sizeFlag := FieldNameSizeFlag(c)
 
-	if sizeFlag.ToSize() > 0 {
+	if sizeFlag.ToSize() > 0 || sizeFlag == FieldNameSizeFlagMixed || sizeFlag == FieldNameSizeFlagString {
 		return sizeFlag, nil
 	}
 	return 0, unexpectedCodeError{code: c, hint: "field length"}
 }
 
+// isMsgpackMap : (UseMsgpackCompat) map 헤더 뒤에 필드명 크기 플래그가 없는 일반 msgpack map인지 확인
+// 다음 바이트가 문자열 코드이면 키가 필드명 문자열인 map으로 본다.
+// 빈 map은 다음 바이트가 크기 플래그(0x00)가 아니면 일반 msgpack map으로 본다.
+func (d *Decoder) isMsgpackMap(n int) (bool, error) {
+	next, err := d.PeekCode()
+	if err != nil {
+		if n == 0 && err == io.EOF {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if n == 0 {
+		return FieldNameSizeFlag(next) != FieldNameSizeFlag1Byte, nil
+	}
+	return msgpcode.IsString(next), nil
+}
+
 func decodeStructValue(d *Decoder, v reflect.Value) error {
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
 
 	// map length
 	c, err := d.readCode()
 	if err != nil {
 		return err
 	}
+	if msgpcode.IsExt(c) {
+		return d.decodeStructExtValue(v, c)
+	}
+	if c == msgpcode.Nil && d.strict() {
+		return errStrictNil(v.Type().String())
+	}
+	return d.decodeStructCode(v, c)
+}
+
+// decodeStructExtValue : interface에 담겨 typeIDExtID ext(타입 ID 0)로 감싸 기록된 struct를 디코딩
+func (d *Decoder) decodeStructExtValue(v reflect.Value, c byte) error {
+	extID, extLen, err := d.extHeader(c)
+	if err != nil {
+		return err
+	}
+	if extID != typeIDExtID {
+		return fmt.Errorf("hpack: got ext type=%d decoding %s", extID, v.Type())
+	}
+
+	start := d.read
+	id, err := d.DecodeUint32()
+	if err != nil {
+		return err
+	}
+	if id != structTypeID {
+		return fmt.Errorf("hpack: got type id %d decoding %s", id, v.Type())
+	}
+	return d.decodeStructExtPayload(v, start, extLen)
+}
 
+// decodeStructExtPayload : 타입 ID 뒤의 struct를 v에 디코딩하고, start부터 읽은 길이가 ext 길이와 같은지 확인
+func (d *Decoder) decodeStructExtPayload(v reflect.Value, start int64, extLen int) error {
+	c, err := d.readCode()
+	if err != nil {
+		return err
+	}
+	if err := d.decodeStructCode(v, c); err != nil {
+		return err
+	}
+	return d.checkExtRead(start, extLen)
+}
+
+// decodeStructCode : map(또는 배열) 코드 c를 읽은 뒤 struct를 디코딩
+func (d *Decoder) decodeStructCode(v reflect.Value, c byte) error {
 	n, err := d.mapLen(c)
 	if err == nil {
+		if n == -1 {
+			return d.decodeStruct(v, n, 0)
+		}
+
+		if d.flags&msgpackCompatFlag != 0 {
+			ok, err := d.isMsgpackMap(n)
+			if err != nil {
+				return err
+			}
+			if ok {
+				// 키가 필드명 문자열이므로 UseFieldNames로 인코딩된 struct와 같이 디코딩
+				return d.decodeStruct(v, n, FieldNameSizeFlagString)
+			}
+		}
 
 		// field hashcode length
 		// 🔴CAUTION: msgpack에 없는 포맷
@@ -334,14 +553,24 @@ func decodeStructValue(d *Decoder, v reflect.Value) error {
 		return nil
 	}
 
-	fields := structs.Fields(v.Type())
-	if n != len(fields.List) {
+	// 필드 추가만 허용: 짧은 배열은 앞쪽 필드만 채우고, 긴 배열의 나머지 값은 건너뛴다.
+	fields, err := structs.Fields(v.Type(), d.hasher, d.tags)
+	if err != nil {
+		return err
+	}
+	if n > len(fields.List) && d.flags&disallowUnknownFieldsFlag != 0 {
 		return errArrayStruct
 	}
 
-	for _, f := range fields.List {
-		if err := f.DecodeValue(d, v); err != nil {
-			return err
+	for i := range n {
+		if i >= len(fields.List) {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fields.List[i].DecodeValue(d, v); err != nil {
+			return d.fieldError(err, v.Type(), fields.List[i])
 		}
 	}
 
@@ -355,45 +584,144 @@ func (d *Decoder) decodeStruct(v reflect.Value, n int, fieldLen FieldNameSizeFla
 		return nil
 	}
 
-	fields := structs.Fields(v.Type())
+	fields, err := structs.Fields(v.Type(), d.hasher, d.tags)
+	if err != nil {
+		return err
+	}
+	if fields.unknown != nil {
+		fields.resetUnknownFields(v)
+	}
+	if err := d.checkFieldLen(fields, fieldLen); err != nil {
+		return newError(err, d.read).withType(v.Type())
+	}
+
+	// FieldNameSizeFlagMixed : 필드별 크기 비트맵
+	var widths []byte
+	if fieldLen == FieldNameSizeFlagMixed {
+		if widths, err = d.decodeMixedWidths(n); err != nil {
+			return err
+		}
+	}
 
-	for range n {
-		fname, err := d.decodeFieldName(fieldLen)
+	// UseStrict : 같은 필드가 두 번 나오거나 필드 순서, 해시 크기 플래그가 Encoder와 다르면 에러
+	seen := newKeySet[*Field](d)
+	seenUnknown := newKeySet[FieldName](d)
+	layout := newStructLayout(d)
+
+	for i := range n {
+		fname, err := d.decodeStructKey(fieldLen, widths, i)
 		if err != nil {
 			return err
 		}
 
-		if f := fields.Map[fname.hash32]; f != nil {
+		var f *Field
+		if fieldLen == FieldNameSizeFlagString {
+			f = fields.byName(fname.name)
+		} else {
+			f = fields.Map[fname.hash32]
+		}
+		if widths != nil {
+			if err := d.checkFieldWidth(fields, f, fname); err != nil {
+				return newError(err, d.read).withField(fname).withType(v.Type())
+			}
+		}
+		if (f != nil && !seen.add(f)) || (f == nil && !seenUnknown.add(fname)) {
+			return newError(strictErrorf("duplicate field"), d.read).withField(fname).withType(v.Type())
+		}
+		if err := layout.add(f, fname); err != nil {
+			return newError(err, d.read).withField(fname).withType(v.Type())
+		}
+		if f != nil {
 			if err := f.DecodeValue(d, v); err != nil {
-				return err
+				return d.fieldError(err, v.Type(), f)
 			}
 			continue
-		} else {
-			fmt.Printf("decodeStruct: unknown field %q(%b) in struct %s\n", fname.size.ToString(), fname.hash32, v.Type().String())
 		}
 
-		if d.flags&disallowUnknownFieldsFlag != 0 {
+		if d.flags&disallowUnknownFieldsFlag != 0 && fields.unknown == nil {
+			if fieldLen == FieldNameSizeFlagString {
+				return fmt.Errorf("hpack: unknown field %q", fname.name)
+			}
 			return fmt.Errorf("hpack: unknown field %q", fname.hash32)
 		}
-		fmt.Printf("Skipping unknown field %q(%b) in struct %s\n", fname.size.ToString(), fname.hash32, v.Type().String())
-		if err := d.Skip(); err != nil {
-			return err
+		if err := d.skipUnknownField(fields, v, fname); err != nil {
+			return newError(err, d.read).withField(fname).withType(v.Type())
 		}
 	}
 
+	if err := layout.check(n, fieldLen); err != nil {
+		return newError(err, d.read).withType(v.Type())
+	}
 	return nil
 }
 
+// skipUnknownField : struct에 없는 필드의 값을 건너뜀
+// `,unknown` 필드가 있으면 값의 바이트를 모으고, OnUnknownField가 설정되어 있으면 값의 바이트와 함께 호출
+func (d *Decoder) skipUnknownField(fields *fields, v reflect.Value, fname FieldName) error {
+	hash := fname.hash32
+	if fname.size == FieldNameSizeFlagString {
+		hash = fields.Hasher.Hash32(fname.name)
+	}
+	if d.logging() {
+		d.logf("hpack: skipping unknown field %s(%d) in struct %s", fname.size.ToString(), hash, fields.Type)
+	}
+
+	if d.onUnknownField == nil && fields.unknown == nil {
+		return d.Skip()
+	}
+
+	raw, err := d.skipRaw()
+	if err != nil {
+		return err
+	}
+	if fields.unknown != nil {
+		fields.addUnknownField(v, UnknownField{Hash: hash, Width: fname.size, Name: fname.name, Value: raw})
+	}
+	if d.onUnknownField != nil {
+		d.onUnknownField(fields.Type, hash, fname.size, raw)
+	}
+	return nil
+}
+
+// fieldWidth : i번째 필드의 해시 크기. widths는 FieldNameSizeFlagMixed일 때의 크기 비트맵
+func fieldWidth(fieldLen FieldNameSizeFlag, widths []byte, i int) FieldNameSizeFlag {
+	if widths == nil {
+		return fieldLen
+	}
+	return FieldNameSizeFlag(widths[i/4] >> (6 - 2*(i%4)) << 6)
+}
+
+// decodeStructKey : i번째 필드의 키를 읽음. FieldNameSizeFlagString이면 필드명(문자열)을 읽는다.
+func (d *Decoder) decodeStructKey(fieldLen FieldNameSizeFlag, widths []byte, i int) (FieldName, error) {
+	if fieldLen == FieldNameSizeFlagString {
+		name, err := d.DecodeString()
+		return FieldName{name: name, size: fieldLen}, err
+	}
+	return d.decodeFieldName(fieldWidth(fieldLen, widths, i))
+}
+
+// decodeMixedWidths : 필드 n개의 크기 비트맵을 읽음
+// 이후 필드 디코딩이 d.buf를 재사용하므로 복사본을 반환한다.
+func (d *Decoder) decodeMixedWidths(n int) ([]byte, error) {
+	b, err := d.readN(mixedWidthsLen(n))
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkMixedWidths(b, n); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
 func (d *Decoder) decodeFieldName(sizeFlag FieldNameSizeFlag) (fname FieldName, err error) {
-	// if intern := d.flags&useInternedStringsFlag != 0; intern || len(d.dict) > 0 {
-	// 	return d.decodeInternedString(intern)
-	// }
+	// 해시 키는 문자열이 아니므로 dict를 사용하지 않는다.
+	// 문자열 키(FieldNameSizeFlagString)는 decodeStructKey에서 DecodeString으로 읽어 dict를 사용한다.
 
 	if sizeFlag.ToSize() <= 0 {
 		return fname, fmt.Errorf("hpack: invalid field name size flag: %v", sizeFlag)
 	}
 
-	b, err := readN(d.r, nil, sizeFlag.ToSize())
+	b, err := d.readN(sizeFlag.ToSize())
 	if err != nil {
 		return fname, err
 	}
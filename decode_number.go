@@ -1,7 +1,6 @@
 package hpack
 
 import (
-	"fmt"
 	"math"
 	"reflect"
 
@@ -88,6 +87,14 @@ func (d *Decoder) DecodeUint64() (uint64, error) {
 }
 
 func (d *Decoder) uint(c byte) (uint64, error) {
+	n, err := d.readUint(c)
+	if err != nil {
+		return 0, err
+	}
+	return n, d.checkShortestInt(c, n)
+}
+
+func (d *Decoder) readUint(c byte) (uint64, error) {
 	if c == msgpcode.Nil {
 		return 0, nil
 	}
@@ -116,7 +123,32 @@ func (d *Decoder) uint(c byte) (uint64, error) {
 	case msgpcode.Uint64, msgpcode.Int64:
 		return d.uint64()
 	}
-	return 0, fmt.Errorf("hpack: invalid code=%x decoding uint64", c)
+	return 0, unexpectedCodeError{code: c, hint: "uint64"}
+}
+
+// intInterface : 정수 코드 c의 값을 코드의 크기와 부호에 맞는 타입으로 반환 (DecodeInterface)
+func (d *Decoder) intInterface(c byte) (interface{}, error) {
+	n, err := d.uint(c)
+	if err != nil {
+		return nil, err
+	}
+	switch c {
+	case msgpcode.Uint8:
+		return uint8(n), nil
+	case msgpcode.Uint16:
+		return uint16(n), nil
+	case msgpcode.Uint32:
+		return uint32(n), nil
+	case msgpcode.Int8:
+		return int8(n), nil
+	case msgpcode.Int16:
+		return int16(n), nil
+	case msgpcode.Int32:
+		return int32(n), nil
+	case msgpcode.Int64:
+		return int64(n), nil
+	}
+	return n, nil
 }
 
 // DecodeInt64 decodes msgpack int8/16/32/64 and uint8/16/32/64
@@ -130,6 +162,14 @@ func (d *Decoder) DecodeInt64() (int64, error) {
 }
 
 func (d *Decoder) int(c byte) (int64, error) {
+	n, err := d.readInt(c)
+	if err != nil {
+		return 0, err
+	}
+	return n, d.checkShortestInt(c, uint64(n))
+}
+
+func (d *Decoder) readInt(c byte) (int64, error) {
 	if c == msgpcode.Nil {
 		return 0, nil
 	}
@@ -159,7 +199,7 @@ func (d *Decoder) int(c byte) (int64, error) {
 		n, err := d.uint64()
 		return int64(n), err
 	}
-	return 0, fmt.Errorf("hpack: invalid code=%x decoding int64", c)
+	return 0, unexpectedCodeError{code: c, hint: "int64"}
 }
 
 func (d *Decoder) DecodeFloat32() (float32, error) {
@@ -167,21 +207,34 @@ func (d *Decoder) DecodeFloat32() (float32, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err := d.checkFloatCode(c, msgpcode.Float); err != nil {
+		return 0, err
+	}
 	return d.float32(c)
 }
 
 func (d *Decoder) float32(c byte) (float32, error) {
-	if c == msgpcode.Float {
+	switch c {
+	case msgpcode.Float:
 		n, err := d.uint32()
 		if err != nil {
 			return 0, err
 		}
 		return math.Float32frombits(n), nil
+	case msgpcode.Double:
+		n, err := d.uint64()
+		if err != nil {
+			return 0, err
+		}
+		return float32(math.Float64frombits(n)), nil
+	case msgpcode.Uint64:
+		n, err := d.uint64()
+		return float32(n), err
 	}
 
 	n, err := d.int(c)
 	if err != nil {
-		return 0, fmt.Errorf("hpack: invalid code=%x decoding float32", c)
+		return 0, unexpectedCodeError{code: c, hint: "float32"}
 	}
 	return float32(n), nil
 }
@@ -192,6 +245,9 @@ func (d *Decoder) DecodeFloat64() (float64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err := d.checkFloatCode(c, msgpcode.Double); err != nil {
+		return 0, err
+	}
 	return d.float64(c)
 }
 
@@ -209,11 +265,14 @@ func (d *Decoder) float64(c byte) (float64, error) {
 			return 0, err
 		}
 		return math.Float64frombits(n), nil
+	case msgpcode.Uint64:
+		n, err := d.uint64()
+		return float64(n), err
 	}
 
 	n, err := d.int(c)
 	if err != nil {
-		return 0, fmt.Errorf("hpack: invalid code=%x decoding float32", c)
+		return 0, unexpectedCodeError{code: c, hint: "float64"}
 	}
 	return float64(n), nil
 }
@@ -22,17 +22,30 @@ func (d *Decoder) arrayLen(c byte) (int, error) {
 	if c == msgpcode.Nil {
 		return -1, nil
 	} else if c >= msgpcode.FixedArrayLow && c <= msgpcode.FixedArrayHigh {
-		return int(c & msgpcode.FixedArrayMask), nil
+		n := int(c & msgpcode.FixedArrayMask)
+		return n, d.checkArrayLen(n)
 	}
+	var n int
 	switch c {
 	case msgpcode.Array16:
-		n, err := d.uint16()
-		return int(n), err
+		v, err := d.uint16()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
 	case msgpcode.Array32:
-		n, err := d.uint32()
-		return int(n), err
+		v, err := d.uint32()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
+	default:
+		return 0, unexpectedCodeError{code: c, hint: "array length"}
 	}
-	return 0, fmt.Errorf("hpack: invalid code=%x decoding array length", c)
+	if err := d.checkShortestLen(c, n); err != nil {
+		return 0, err
+	}
+	return n, d.checkArrayLen(n)
 }
 
 func decodeStringSliceValue(d *Decoder, v reflect.Value) error {
@@ -48,8 +61,11 @@ func (d *Decoder) decodeStringSlicePtr(ptr *[]string) error {
 	if n == -1 {
 		return nil
 	}
+	if err := d.allocLen(n, stringType.Size()); err != nil {
+		return err
+	}
 
-	ss := makeStrings(*ptr, n, d.flags&disableAllocLimitFlag != 0)
+	ss := makeStrings(*ptr, d.allocCap(n, sliceAllocLimit))
 	for i := 0; i < n; i++ {
 		s, err := d.DecodeString()
 		if err != nil {
@@ -62,11 +78,7 @@ func (d *Decoder) decodeStringSlicePtr(ptr *[]string) error {
 	return nil
 }
 
-func makeStrings(s []string, n int, noLimit bool) []string {
-	if !noLimit && n > sliceAllocLimit {
-		n = sliceAllocLimit
-	}
-
+func makeStrings(s []string, n int) []string {
 	if s == nil {
 		return make([]string, 0, n)
 	}
@@ -94,6 +106,13 @@ func decodeSliceValue(d *Decoder, v reflect.Value) error {
 		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
 		return nil
 	}
+	if err := d.allocLen(n, v.Type().Elem().Size()); err != nil {
+		return err
+	}
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
 
 	if v.Cap() >= n {
 		v.Set(v.Slice(0, n))
@@ -101,31 +120,29 @@ func decodeSliceValue(d *Decoder, v reflect.Value) error {
 		v.Set(v.Slice(0, v.Cap()))
 	}
 
-	noLimit := d.flags&disableAllocLimitFlag != 1
+	noLimit := d.flags&disableAllocLimitFlag != 0
 
 	if noLimit && n > v.Len() {
-		v.Set(growSliceValue(v, n, noLimit))
+		v.Set(d.growSliceValue(v, n))
 	}
 
 	for i := 0; i < n; i++ {
 		if !noLimit && i >= v.Len() {
-			v.Set(growSliceValue(v, n, noLimit))
+			v.Set(d.growSliceValue(v, n))
 		}
 
 		elem := v.Index(i)
 		if err := d.DecodeValue(elem); err != nil {
-			return err
+			return d.elemError(err, indexSegment(i))
 		}
 	}
 
 	return nil
 }
 
-func growSliceValue(v reflect.Value, n int, noLimit bool) reflect.Value {
-	diff := n - v.Len()
-	if !noLimit && diff > sliceAllocLimit {
-		diff = sliceAllocLimit
-	}
+// growSliceValue : 길이 n까지 남은 원소를 늘림. 한 번에 늘리는 수는 allocCap으로 제한하되 최소 1개
+func (d *Decoder) growSliceValue(v reflect.Value, n int) reflect.Value {
+	diff := max(d.allocCap(n-v.Len(), sliceAllocLimit), 1)
 	v = reflect.AppendSlice(v, reflect.MakeSlice(v.Type(), diff, diff))
 	return v
 }
@@ -137,16 +154,23 @@ func decodeArrayValue(d *Decoder, v reflect.Value) error {
 	}
 
 	if n == -1 {
+		if d.strict() {
+			return errStrictNil(v.Type().String())
+		}
 		return nil
 	}
 	if n > v.Len() {
 		return fmt.Errorf("%s len is %d, but msgpack has %d elements", v.Type(), v.Len(), n)
 	}
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
 
 	for i := 0; i < n; i++ {
 		sv := v.Index(i)
 		if err := d.DecodeValue(sv); err != nil {
-			return err
+			return d.elemError(err, indexSegment(i))
 		}
 	}
 
@@ -169,12 +193,20 @@ func (d *Decoder) decodeSlice(c byte) ([]interface{}, error) {
 	if n == -1 {
 		return nil, nil
 	}
+	if err := d.allocLen(n, interfaceType.Size()); err != nil {
+		return nil, err
+	}
+	if err := d.enterDepth(); err != nil {
+		return nil, err
+	}
+	defer d.leaveDepth()
 
-	s := make([]interface{}, 0, n)
+	ln := d.allocCap(n, sliceAllocLimit)
+	s := make([]interface{}, 0, ln)
 	for i := 0; i < n; i++ {
 		v, err := d.decodeInterfaceCond()
 		if err != nil {
-			return nil, err
+			return nil, d.elemError(err, indexSegment(i))
 		}
 		s = append(s, v)
 	}
@@ -187,6 +219,10 @@ func (d *Decoder) skipSlice(c byte) error {
 	if err != nil {
 		return err
 	}
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
 
 	for i := 0; i < n; i++ {
 		if err := d.Skip(); err != nil {
@@ -13,25 +13,47 @@ func (d *Decoder) bytesLen(c byte) (int, error) {
 	}
 
 	if msgpcode.IsFixedString(c) {
-		return int(c & msgpcode.FixedStrMask), nil
+		n := int(c & msgpcode.FixedStrMask)
+		return n, d.checkLen("MaxStringLen", d.limits.MaxStringLen, n, 1)
 	}
 
+	var n int
 	switch c {
 	case msgpcode.Str8, msgpcode.Bin8:
-		n, err := d.uint8()
-		return int(n), err
+		v, err := d.uint8()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
 	case msgpcode.Str16, msgpcode.Bin16:
-		n, err := d.uint16()
-		return int(n), err
+		v, err := d.uint16()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
 	case msgpcode.Str32, msgpcode.Bin32:
-		n, err := d.uint32()
-		return int(n), err
+		v, err := d.uint32()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
+	default:
+		return 0, unexpectedCodeError{code: c, hint: "string/bytes length"}
+	}
+	if err := d.checkShortestLen(c, n); err != nil {
+		return 0, err
 	}
 
-	return 0, fmt.Errorf("hpack: invalid code=%x decoding string/bytes length", c)
+	if c == msgpcode.Bin8 || c == msgpcode.Bin16 || c == msgpcode.Bin32 {
+		return n, d.checkLen("MaxBinLen", d.limits.MaxBinLen, n, 1)
+	}
+	return n, d.checkLen("MaxStringLen", d.limits.MaxStringLen, n, 1)
 }
 
 func (d *Decoder) DecodeString() (string, error) {
+	if err := d.checkNextNotNil("string"); err != nil {
+		return "", err
+	}
 	if intern := d.flags&useInternedStringsFlag != 0; intern || len(d.dict) > 0 {
 		return d.decodeInternedString(intern)
 	}
@@ -43,6 +65,29 @@ func (d *Decoder) DecodeString() (string, error) {
 	return d.string(c)
 }
 
+// stringCond : UseInternedStrings이면 읽은 문자열을 dict에 추가
+// 코드를 이미 읽은 경로(DecodeInterface, Skip)에서 인코더의 dict와 맞추기 위해 사용
+func (d *Decoder) stringCond(c byte) (string, error) {
+	if d.flags&useInternedStringsFlag == 0 {
+		return d.string(c)
+	}
+
+	n, err := d.bytesLen(c)
+	if err != nil {
+		return "", err
+	}
+	return d.decodeInternedStringWithLen(n, true)
+}
+
+// skipString : UseInternedStrings이면 건너뛰는 문자열도 dict에 추가해야 이후 인덱스가 맞는다.
+func (d *Decoder) skipString(c byte) error {
+	if d.flags&useInternedStringsFlag == 0 {
+		return d.skipBytes(c)
+	}
+	_, err := d.stringCond(c)
+	return err
+}
+
 func (d *Decoder) string(c byte) (string, error) {
 	n, err := d.bytesLen(c)
 	if err != nil {
@@ -92,7 +137,7 @@ func (d *Decoder) bytes(c byte, b []byte) ([]byte, error) {
 	if n == -1 {
 		return nil, nil
 	}
-	return readN(d.r, b, n)
+	return d.readBytes(b, n)
 }
 
 /*
@@ -141,7 +186,7 @@ func (d *Decoder) bytesPtr(c byte, ptr *[]byte) error {
 		return nil
 	}
 
-	*ptr, err = readN(d.r, *ptr, n)
+	*ptr, err = d.readBytes(*ptr, n)
 	return err
 }
 
@@ -0,0 +1,276 @@
+package hpack_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type withMap struct {
+	M    map[int]string `msgpack:"m"`
+	Tail string         `msgpack:"tail"`
+}
+
+type withStruct struct {
+	Pos  fuzzBase `msgpack:"pos"`
+	Tail string   `msgpack:"tail"`
+}
+
+type withAny struct {
+	Any  interface{} `msgpack:"any"`
+	Tail string      `msgpack:"tail"`
+}
+
+type anyPos struct {
+	Pos  interface{} `msgpack:"pos"`
+	Tail string      `msgpack:"tail"`
+}
+
+type tailOnly struct {
+	Tail string `msgpack:"tail"`
+}
+
+func TestSkipUnknownField(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+	}{
+		{"map first key 0", &withMap{M: map[int]string{0: "a"}, Tail: "zz"}},
+		{"map first key 32", &withMap{M: map[int]string{32: "b"}, Tail: "zz"}},
+		{"map first key 64", &withMap{M: map[int]string{64: "c", 1: "d"}, Tail: "zz"}},
+		{"empty map", &withMap{M: map[int]string{}, Tail: "zz"}},
+		{"struct in interface", &withAny{Any: &fuzzItem{ID: 1, Tags: []string{"t"}}, Tail: "zz"}},
+		{"int map in interface", &withAny{Any: map[int]string{32: "b"}, Tail: "zz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hpack.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out tailOnly
+			if err := hpack.Unmarshal(b, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.Tail != "zz" {
+				t.Fatalf("Tail = %q, want %q", out.Tail, "zz")
+			}
+
+			// 타입 정보 없이 건너뛸 수 있도록 interface 슬라이스에 담는다.
+			b, err = hpack.Marshal([]interface{}{tt.in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := hpack.NewDecoder(bytes.NewReader(b))
+			if err := dec.Skip(); err != nil {
+				t.Fatal(err)
+			}
+			if n := dec.InputOffset(); n != int64(len(b)) {
+				t.Fatalf("Skip read %d bytes, want %d", n, len(b))
+			}
+		})
+	}
+}
+
+func TestDecodeInterfaceMap(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{map[int]string{0: "a"}, map[interface{}]interface{}{int8(0): "a"}},
+		{map[int]string{5: "e"}, map[interface{}]interface{}{int8(5): "e"}},
+		{map[int]string{32: "b"}, map[interface{}]interface{}{int8(32): "b"}},
+		{map[string]int{"a": 1}, map[string]interface{}{"a": int8(1)}},
+		{map[string]int{}, map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		b, err := hpack.Marshal(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v interface{}
+		if err := hpack.Unmarshal(b, &v); err != nil {
+			t.Fatalf("%v: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Fatalf("%v: got %#v, want %#v", tt.in, v, tt.want)
+		}
+	}
+}
+
+func TestDecodeInterfaceStruct(t *testing.T) {
+	// struct는 ext 없이 map 헤더로 시작한다.
+	b, err := hpack.Marshal(&fuzzBase{Seq: 7, Flag: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != hpack.FixedMapLow|2 || hpack.FieldNameSizeFlag(b[1]) != hpack.FieldNameSizeFlag1Byte {
+		t.Fatalf("header = % x", b[:2])
+	}
+
+	// interface에 담긴 struct만 ext로 감싼다.
+	v := decodeAny(t, &fuzzBase{Seq: 7, Flag: true}, nil)
+	m, ok := v.(map[uint32]interface{})
+	if !ok || len(m) != 2 {
+		t.Fatalf("got %#v, want map[uint32]interface{} with 2 fields", v)
+	}
+
+	// 하위 struct도 감싸므로 타입 정보 없이 읽을 수 있다.
+	v = decodeAny(t, &withStruct{Pos: fuzzBase{Seq: 7}, Tail: "zz"}, nil)
+	if m, ok := v.(map[uint32]interface{}); !ok || len(m) != 2 {
+		t.Fatalf("got %#v", v)
+	}
+	for _, f := range v.(map[uint32]interface{}) {
+		if f != "zz" {
+			if pos, ok := f.(map[uint32]interface{}); !ok || len(pos) != 2 {
+				t.Fatalf("pos = %#v", f)
+			}
+		}
+	}
+
+	v = decodeAny(t, &fuzzBase{Seq: 7}, func(enc *hpack.Encoder) { enc.UseFieldNames(true) })
+	want := map[string]interface{}{"seq": int8(7), "flag": false}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("got %#v, want %#v", v, want)
+	}
+
+	// 포인터가 담긴 interface는 가리키는 struct에 디코딩한다.
+	base := &fuzzBase{}
+	out := withAny{Any: base}
+	if err := hpack.Unmarshal(encode(t, &withAny{Any: &fuzzBase{Seq: 7}}, nil), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Any != base || base.Seq != 7 {
+		t.Fatalf("got %#v", out.Any)
+	}
+
+	// 감싼 struct를 struct 타입 필드로도 읽는다.
+	var typed withStruct
+	if err := hpack.Unmarshal(encode(t, &anyPos{Pos: &fuzzBase{Seq: 7}, Tail: "zz"}, nil), &typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Pos.Seq != 7 || typed.Tail != "zz" {
+		t.Fatalf("got %+v", typed)
+	}
+}
+
+// decodeAny : v를 interface 슬라이스에 담아 인코딩한 뒤 타입 정보 없이 디코딩한 값
+func decodeAny(t *testing.T, v interface{}, setup func(*hpack.Encoder)) interface{} {
+	t.Helper()
+	var out []interface{}
+	if err := hpack.Unmarshal(encode(t, []interface{}{v}, setup), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("got %#v", out)
+	}
+	return out[0]
+}
+
+func decode(b []byte, v interface{}, setup func(*hpack.Decoder)) error {
+	dec := hpack.NewDecoder(bytes.NewReader(b))
+	if setup != nil {
+		setup(dec)
+	}
+	return dec.Decode(v)
+}
+
+func TestMsgpackCompat(t *testing.T) {
+	in := namedOuter{ID: 1, Name: "kim", Inner: namedInner{Level: 2}, List: []namedInner{{Level: 3}}}
+	b, err := msgpack.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compat := func(dec *hpack.Decoder) { dec.UseMsgpackCompat(true) }
+
+	var out namedOuter
+	if err := decode(b, &out, compat); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+	if err := decode(b, &namedOuter{}, nil); err == nil {
+		t.Fatal("without UseMsgpackCompat: want error")
+	}
+
+	// hpack으로 인코딩된 데이터도 그대로 읽는다.
+	hb, err := hpack.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = namedOuter{}
+	if err := decode(hb, &out, compat); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("hpack input: got %+v, err %v", out, err)
+	}
+
+	// 모르는 키는 건너뛰고, DisallowUnknownFields이면 에러
+	extra, err := msgpack.Marshal(map[string]interface{}{"level": 5, "unknown": []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inner namedInner
+	if err := decode(extra, &inner, compat); err != nil || inner.Level != 5 {
+		t.Fatalf("got %+v, err %v", inner, err)
+	}
+	err = decode(extra, &inner, func(dec *hpack.Decoder) {
+		dec.UseMsgpackCompat(true)
+		dec.DisallowUnknownFields(true)
+	})
+	if err == nil {
+		t.Fatal("DisallowUnknownFields: want error")
+	}
+}
+
+// onlyReader : bufio.Reader가 아닌 io.Reader (Decoder가 내부에서 bufio로 감싼다)
+type onlyReader struct{ r io.Reader }
+
+func (r onlyReader) Read(p []byte) (int, error) { return r.r.Read(p) }
+
+func TestInputOffset(t *testing.T) {
+	// 타입 정보 없이 읽을 수 있도록 struct는 interface 슬라이스에 담는다.
+	msgs := []interface{}{[]interface{}{&namedOuter{ID: 1, Name: "kim"}}, "hello", []int{1, 2, 3}, []interface{}{&dPos{X: 1}}}
+	var stream []byte
+	var ends []int64
+	for _, m := range msgs {
+		b, err := hpack.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream = append(stream, b...)
+		ends = append(ends, int64(len(stream)))
+	}
+
+	readers := map[string]func() io.Reader{
+		"bytes.Reader": func() io.Reader { return bytes.NewReader(stream) },
+		"io.Reader":    func() io.Reader { return onlyReader{bytes.NewReader(stream)} },
+		"small bufio":  func() io.Reader { return bufio.NewReaderSize(bytes.NewReader(stream), 16) },
+	}
+	for name, newReader := range readers {
+		t.Run(name, func(t *testing.T) {
+			dec := hpack.NewDecoder(newReader())
+			for i, end := range ends {
+				if _, err := dec.PeekCode(); err != nil {
+					t.Fatal(err)
+				}
+				if i == 1 {
+					if err := dec.Skip(); err != nil {
+						t.Fatal(err)
+					}
+				} else if _, err := dec.DecodeInterface(); err != nil {
+					t.Fatal(err)
+				}
+				if got := dec.InputOffset(); got != end {
+					t.Fatalf("message %d: offset %d, want %d", i, got, end)
+				}
+			}
+
+			dec.Reset(newReader())
+			if dec.InputOffset() != 0 {
+				t.Fatalf("offset after Reset = %d", dec.InputOffset())
+			}
+		})
+	}
+}
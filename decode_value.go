@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
 )
 
 // var (
@@ -182,7 +184,52 @@ func decodeInterfaceValue(d *Decoder, v reflect.Value) error {
 	if v.IsNil() {
 		return d.interfaceValue(v)
 	}
-	return d.DecodeValue(v.Elem())
+	// 기존 값에 덮어쓸 수 있는 건 포인터뿐이다. (interface 안의 값은 settable이 아님)
+	// 등록된 타입(RegisterType)은 기존 값과 타입이 다를 수 있으므로 새로 만든다.
+	elem := v.Elem()
+	if elem.Kind() != reflect.Ptr || elem.IsNil() {
+		return d.interfaceValue(v)
+	}
+	if c, err := d.PeekCode(); err == nil && msgpcode.IsExt(c) {
+		return d.interfaceExtValue(v)
+	}
+	// interface에서 꺼낸 포인터는 settable이 아니므로 가리키는 값에 디코딩
+	return d.DecodeValue(elem.Elem())
+}
+
+// interfaceExtValue : 포인터가 담긴 interface v에 ext를 디코딩
+// 등록되지 않은 struct(structTypeID)는 포인터가 가리키는 struct에, 그 밖의 값은 새 값으로 디코딩
+func (d *Decoder) interfaceExtValue(v reflect.Value) error {
+	c, err := d.readCode()
+	if err != nil {
+		return err
+	}
+	extID, extLen, err := d.extHeader(c)
+	if err != nil {
+		return err
+	}
+	if extID != typeIDExtID || extLen == 0 {
+		vv, err := d.decodeExtInterface(extID, extLen)
+		if err != nil {
+			return err
+		}
+		return d.setInterface(v, vv)
+	}
+
+	start := d.read
+	id, err := d.DecodeUint32()
+	if err != nil {
+		return err
+	}
+	if elem := v.Elem().Elem(); id == structTypeID && elem.Kind() == reflect.Struct {
+		return d.decodeStructExtPayload(elem, start, extLen)
+	}
+
+	vv, err := d.decodeTypeIDValue(id, start, extLen)
+	if err != nil {
+		return err
+	}
+	return d.setInterface(v, vv)
 }
 
 func (d *Decoder) interfaceValue(v reflect.Value) error {
@@ -190,7 +237,11 @@ func (d *Decoder) interfaceValue(v reflect.Value) error {
 	if err != nil {
 		return err
 	}
+	return d.setInterface(v, vv)
+}
 
+// setInterface : DecodeInterface로 읽은 vv를 interface v에 채움
+func (d *Decoder) setInterface(v reflect.Value, vv interface{}) error {
 	if vv != nil {
 		if v.Type() == errorType {
 			if vv, ok := vv.(string); ok {
@@ -199,6 +250,10 @@ func (d *Decoder) interfaceValue(v reflect.Value) error {
 			}
 		}
 
+		vt := reflect.TypeOf(vv)
+		if !vt.AssignableTo(v.Type()) {
+			return fmt.Errorf("hpack: Decode(%s does not implement %s)", vt, v.Type())
+		}
 		v.Set(reflect.ValueOf(vv))
 	}
 
@@ -2,6 +2,7 @@ package hpack
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"reflect"
 	"sync"
@@ -15,24 +16,33 @@ const (
 	useCompactFloatsFlag
 	useInternedStringsFlag
 	omitEmptyFlag
+	fieldNamesFlag
+	structExtFlag // interface에 담긴 struct를 인코딩하는 중. 하위 struct도 ext로 감싼다.
 )
 
 func Marshal(v interface{}) ([]byte, error) {
-	enc := GetEncoder()
+	b, err := AppendMarshal(nil, v)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
 
-	var buf bytes.Buffer
-	enc.Reset(&buf)
+// AppendMarshal appends the MessagePack encoding of v to dst and returns the extended buffer.
+// On error dst is returned unchanged.
+// Reusing dst (e.g. AppendMarshal(buf[:0], v)) avoids allocations for structs without interfaces and maps.
+func AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	enc := GetEncoder()
+	enc.Reset(nil)
 
-	err := enc.Encode(v)
-	b := buf.Bytes()
+	b, err := enc.AppendEncode(dst, v)
 
 	PutEncoder(enc)
 
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
-
-	return b, err
+	return b, nil
 }
 
 var encPool = sync.Pool{
@@ -55,6 +65,26 @@ type writer interface {
 	WriteByte(byte) error
 	Len() int
 }
+
+// appendWriter : 호출한 쪽의 []byte 뒤에 기록 (AppendEncode)
+type appendWriter struct {
+	b []byte
+}
+
+func (aw *appendWriter) Write(p []byte) (int, error) {
+	aw.b = append(aw.b, p...)
+	return len(p), nil
+}
+
+func (aw *appendWriter) WriteByte(c byte) error {
+	aw.b = append(aw.b, c)
+	return nil
+}
+
+func (aw *appendWriter) Len() int {
+	return len(aw.b)
+}
+
 type byteWriter struct {
 	io.Writer
 }
@@ -68,12 +98,17 @@ func (bw byteWriter) Len() int {
 }
 
 type Encoder struct {
-	w         writer
-	dict      map[string]int
-	structTag string
-	buf       []byte
-	timeBuf   []byte
-	flags     uint32
+	w       writer
+	aw      appendWriter
+	dict    map[string]int
+	tags    structTags
+	hasher  FieldHasher
+	buf     []byte
+	timeBuf []byte
+	flags   uint32
+
+	// omitempty로 걸러낸 struct 필드. 중첩된 struct는 뒤에 이어 쌓고, 인코딩이 끝나면 되돌린다.
+	omitFields []*Field
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -96,8 +131,9 @@ func (e *Encoder) Reset(w io.Writer) {
 }
 func (e *Encoder) ResetDict(w io.Writer, dict map[string]int) {
 	e.ResetWriter(w)
-	e.flags = 0
-	e.structTag = ""
+	e.flags = useCompactIntsFlag
+	e.tags = structTags{}
+	e.hasher = nil
 	e.dict = dict
 }
 func (e *Encoder) ResetWriter(w io.Writer) {
@@ -110,12 +146,135 @@ func (e *Encoder) ResetWriter(w io.Writer) {
 		e.w = newByteWriter(w)
 	}
 }
+
+// SetCustomStructTag causes the Encoder to use the supplied tag as a fallback option
+// if there is no msgpack tag.
+func (e *Encoder) SetCustomStructTag(tag string) {
+	e.tags.fallback = tag
+}
+
+// SetStructTags causes the Encoder to read field names and options from the primary tag
+// (msgpack if empty), and from the fallback tag when the primary tag is missing.
+// e.g. SetStructTags("hpack", "json")
+func (e *Encoder) SetStructTags(primary, fallback string) {
+	e.tags = structTags{primary: primary, fallback: fallback}
+}
+
+// SetFieldHasher causes the Encoder to hash struct field names with hasher.
+// Types registered with RegisterFieldHasher keep their own hasher. nil restores the default (CRC32-IEEE).
+func (e *Encoder) SetFieldHasher(hasher FieldHasher) {
+	e.hasher = hasher
+}
+
+// SetSortMapKeys causes the Encoder to encode map keys in increasing order,
+// so equal maps always produce equal bytes.
+// Supported key types are strings, bools, ints, uints, floats and encoding.TextMarshaler;
+// other key types return an error.
+func (e *Encoder) SetSortMapKeys(on bool) {
+	if on {
+		e.flags |= sortMapKeysFlag
+	} else {
+		e.flags &= ^sortMapKeysFlag
+	}
+}
+
+// UseInternedStrings causes the Encoder to intern every string: the first occurrence is written
+// as a normal string and added to the dictionary, later ones are written as a dictionary index.
+// The Decoder must use UseInternedStrings too.
+func (e *Encoder) UseInternedStrings(on bool) {
+	if on {
+		e.flags |= useInternedStringsFlag
+	} else {
+		e.flags &= ^useInternedStringsFlag
+	}
+}
+
+// WithDict calls fn with dict as the interned string dictionary and restores the previous one.
+// Strings interned while fn runs are added to dict, so it can be shared across messages.
+func (e *Encoder) WithDict(dict map[string]int, fn func(*Encoder) error) error {
+	oldDict := e.dict
+	e.dict = dict
+	err := fn(e)
+	e.dict = oldDict
+	return err
+}
+
+// UseCompactInts causes the Encoder to encode Go integers in the shortest msgpack form
+// regardless of their type. It is on by default; when off, int8..int64 and uint8..uint64
+// are written with the width of their type (int and uint stay compact).
+func (e *Encoder) UseCompactInts(on bool) {
+	if on {
+		e.flags |= useCompactIntsFlag
+	} else {
+		e.flags &= ^useCompactIntsFlag
+	}
+}
+
+// UseCompactFloats causes the Encoder to write integral floats as msgpack ints
+// and float64 values that are exactly representable as float32 as msgpack Float.
+// Decoders accept any numeric code into float fields, so no decoder option is needed.
+func (e *Encoder) UseCompactFloats(on bool) {
+	if on {
+		e.flags |= useCompactFloatsFlag
+	} else {
+		e.flags &= ^useCompactFloatsFlag
+	}
+}
+
+// UseArrayEncodedStructs causes the Encoder to encode Go structs as msgpack arrays
+// of field values in declaration order, without field names.
+// Structs with an as_array option on a _hpack (or _msgpack) field are always encoded as arrays.
+func (e *Encoder) UseArrayEncodedStructs(on bool) {
+	if on {
+		e.flags |= arrayEncodedStructsFlag
+	} else {
+		e.flags &= ^arrayEncodedStructsFlag
+	}
+}
+
+// SetOmitEmpty causes the Encoder to omit empty values of every struct field,
+// as if every field had the omitempty tag option.
+func (e *Encoder) SetOmitEmpty(on bool) {
+	if on {
+		e.flags |= omitEmptyFlag
+	} else {
+		e.flags &= ^omitEmptyFlag
+	}
+}
+
+// UseFieldNames causes the Encoder to write struct field names as msgpack strings
+// instead of hashes (FieldNameSizeFlagString), so payloads can be read in a msgpack viewer.
+// Decoders accept both forms without any option.
+func (e *Encoder) UseFieldNames(on bool) {
+	if on {
+		e.flags |= fieldNamesFlag
+	} else {
+		e.flags &= ^fieldNamesFlag
+	}
+}
+
 func newByteWriter(w io.Writer) byteWriter {
 	return byteWriter{
 		Writer: w,
 	}
 }
 
+// AppendEncode appends the encoding of v to dst and returns the extended buffer,
+// using the Encoder's options and dictionary instead of its writer.
+// On error the returned buffer holds a partial encoding.
+func (e *Encoder) AppendEncode(dst []byte, v interface{}) ([]byte, error) {
+	w := e.w
+	e.aw.b = dst
+	e.w = &e.aw
+
+	err := e.Encode(v)
+
+	b := e.aw.b
+	e.aw.b = nil
+	e.w = w
+	return b, err
+}
+
 func (e *Encoder) Encode(v interface{}) error {
 	switch v := v.(type) {
 	case nil:
@@ -127,22 +286,19 @@ func (e *Encoder) Encode(v interface{}) error {
 	case int:
 		return e.EncodeInt(int64(v))
 	case int64:
-		// return e.encodeInt64Cond(v) use compact?
-		return e.EncodeInt(v)
+		return e.encodeInt64Cond(v)
 	case uint:
 		return e.EncodeUint(uint64(v))
 	case uint64:
-		// return e.encodeUint64Cond(v) use compact?
-		return e.EncodeUint(v)
+		return e.encodeUint64Cond(v)
 	case bool:
 		return e.EncodeBool(v)
 	case float32:
-		return e.EncodeFloat32(v) // use compact?
+		return e.EncodeFloat32(v)
 	case float64:
-		return e.EncodeFloat64(v) // use compact?
+		return e.EncodeFloat64(v)
 	case time.Duration:
-		// return e.encodeInt64Cond(int64(v)) // use compact?
-		return e.EncodeInt(int64(v))
+		return e.encodeInt64Cond(int64(v))
 	case time.Time:
 		return e.EncodeTime(v)
 	}
@@ -164,11 +320,11 @@ func (e *Encoder) EncodeBool(value bool) error {
 	return e.writeCode(False)
 }
 func (f *Field) encodeFieldName(e *Encoder, fieldLen FieldNameSizeFlag) error {
-	buf, err := f.fieldName.toBuffer(fieldLen)
-	if err != nil {
-		return err
+	size := fieldLen.ToSize()
+	if size <= 0 {
+		return fmt.Errorf("invalid hash size: %d", fieldLen)
 	}
-	return e.write(buf)
+	return e.write(f.key[4-size:])
 }
 
 // encodeFieldName: 문자열 필드명을 Header를 붙인 Hashcode로 변환
@@ -1,9 +1,12 @@
 package hpack
 
 import (
+	"cmp"
+	"encoding"
 	"fmt"
 	"math"
 	"reflect"
+	"slices"
 	"sort"
 )
 
@@ -29,8 +32,8 @@ func (e *Encoder) EncodeMap(m map[string]interface{}) error {
 		if err := e.EncodeString(mk); err != nil {
 			return err
 		}
-		if err := e.Encode(mv); err != nil {
-			return err
+		if err := e.encodeInterface(mv); err != nil {
+			return e.elemError(err, keySegment(reflect.ValueOf(mk)))
 		}
 	}
 	return nil
@@ -98,16 +101,63 @@ func (e *Encoder) encodeMapLen(l int) error {
 	return e.write4(Map32, uint32(l))
 }
 
-func (e *Encoder) encodeFieldLen(fields []*Field) (fieldLen FieldNameSizeFlag, err error) {
+// encodeFieldLen : 필드명 해시 크기 플래그를 기록. ufs는 fields 뒤에 기록할 보존 필드(UnknownFields)
+// 모든 필드를 최대 크기로 맞추는 것보다 필드별 크기 비트맵을 두는 쪽이 작으면 FieldNameSizeFlagMixed를 사용한다.
+func (e *Encoder) encodeFieldLen(fields []*Field, ufs UnknownFields) (fieldLen FieldNameSizeFlag, err error) {
 	fieldLen = maxFieldLen(fields)
+	for _, uf := range ufs {
+		if uf.Width.ToSize() > fieldLen.ToSize() {
+			fieldLen = uf.Width
+		}
+	}
 
 	if fieldLen.ToSize() <= 0 {
 		return fieldLen, fmt.Errorf("hpack: invalid field name size flag: %v", fieldLen)
 	}
 
+	if fieldLen != FieldNameSizeFlag1Byte {
+		sum := 0
+		for _, f := range fields {
+			sum += f.fieldName.size.ToSize()
+		}
+		for _, uf := range ufs {
+			sum += uf.Width.ToSize()
+		}
+		if fieldLenLayout(len(fields)+len(ufs), fieldLen, sum) == FieldNameSizeFlagMixed {
+			return FieldNameSizeFlagMixed, e.encodeMixedWidths(fields, ufs)
+		}
+	}
+
 	return fieldLen, e.writeCode(byte(fieldLen))
 }
 
+// fieldLenLayout : 해시 크기의 최대값이 max, 합이 sum인 필드 n개에 사용할 크기 플래그
+// 모든 필드를 max로 맞추는 것보다 필드별 크기 비트맵을 두는 쪽이 작으면 FieldNameSizeFlagMixed
+func fieldLenLayout(n int, max FieldNameSizeFlag, sum int) FieldNameSizeFlag {
+	if max != FieldNameSizeFlag1Byte && mixedWidthsLen(n)+sum < n*max.ToSize() {
+		return FieldNameSizeFlagMixed
+	}
+	return max
+}
+
+// encodeMixedWidths : FieldNameSizeFlagMixed 플래그와 필드별 크기 비트맵을 기록
+func (e *Encoder) encodeMixedWidths(fields []*Field, ufs UnknownFields) error {
+	if err := e.writeCode(byte(FieldNameSizeFlagMixed)); err != nil {
+		return err
+	}
+
+	e.buf = grow(e.buf, mixedWidthsLen(len(fields)+len(ufs)))
+	clear(e.buf)
+	for i, f := range fields {
+		e.buf[i/4] |= byte(f.fieldName.size) >> 6 << (6 - 2*(i%4))
+	}
+	for j, uf := range ufs {
+		i := len(fields) + j
+		e.buf[i/4] |= byte(uf.Width) >> 6 << (6 - 2*(i%4))
+	}
+	return e.write(e.buf)
+}
+
 func encodeMapValue(e *Encoder, v reflect.Value) error {
 	if v.IsNil() {
 		return e.EncodeNil()
@@ -117,19 +167,108 @@ func encodeMapValue(e *Encoder, v reflect.Value) error {
 		return err
 	}
 
+	if e.flags&sortMapKeysFlag != 0 {
+		return e.encodeSortedMapValue(v)
+	}
+
 	iter := v.MapRange()
 	for iter.Next() {
 		if err := e.EncodeValue(iter.Key()); err != nil {
 			return err
 		}
 		if err := e.EncodeValue(iter.Value()); err != nil {
+			return e.elemError(err, keySegment(iter.Key()))
+		}
+	}
+
+	return nil
+}
+
+// encodeSortedMapValue : 키를 정렬하여 map의 키/값을 기록 (SetSortMapKeys)
+func (e *Encoder) encodeSortedMapValue(v reflect.Value) error {
+	keys, err := sortedMapKeys(v)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := e.EncodeValue(k); err != nil {
 			return err
 		}
+		if err := e.EncodeValue(v.MapIndex(k)); err != nil {
+			return e.elemError(err, keySegment(k))
+		}
 	}
 
 	return nil
 }
 
+// sortedMapKeys : map의 키를 오름차순으로 정렬하여 반환
+// TextMarshaler 키는 인코딩되는 텍스트 순서, false < true, 실수의 NaN은 가장 앞에 온다.
+func sortedMapKeys(v reflect.Value) ([]reflect.Value, error) {
+	keys := v.MapKeys()
+	typ := v.Type().Key()
+
+	if typ.Implements(textMarshalerType) {
+		type textKey struct {
+			key  reflect.Value
+			text string
+		}
+		textKeys := make([]textKey, len(keys))
+		for i, k := range keys {
+			textKeys[i].key = k
+			if k.Kind() == reflect.Ptr && k.IsNil() {
+				continue
+			}
+			text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			textKeys[i].text = string(text)
+		}
+		slices.SortFunc(textKeys, func(a, b textKey) int {
+			return cmp.Compare(a.text, b.text)
+		})
+		for i := range textKeys {
+			keys[i] = textKeys[i].key
+		}
+		return keys, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.String(), b.String())
+		})
+	case reflect.Bool:
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			if a.Bool() == b.Bool() {
+				return 0
+			}
+			if b.Bool() {
+				return -1
+			}
+			return 1
+		})
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.Int(), b.Int())
+		})
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.Uint(), b.Uint())
+		})
+	case reflect.Float32, reflect.Float64:
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.Float(), b.Float())
+		})
+	default:
+		return nil, fmt.Errorf("hpack: SetSortMapKeys: unsupported map key type %s", typ)
+	}
+
+	return keys, nil
+}
+
 func maxFieldLen(fields []*Field) FieldNameSizeFlag {
 	l := FieldNameSizeFlag1Byte
 
@@ -142,39 +281,114 @@ func maxFieldLen(fields []*Field) FieldNameSizeFlag {
 }
 
 func encodeStructValue(e *Encoder, strct reflect.Value) error {
-	structFields := structs.Fields(strct.Type())
+	structFields, err := structs.Fields(strct.Type(), e.hasher, e.tags)
+	if err != nil {
+		return err
+	}
 
+	if structFields.AsArray || e.flags&arrayEncodedStructsFlag != 0 {
+		return encodeStructValueAsArray(e, strct, structFields.List)
+	}
+	if e.flags&structExtFlag != 0 {
+		return e.encodeStructExt(strct, structFields)
+	}
+	return e.encodeStructMap(strct, structFields)
+}
+
+// encodeStructExt : interface에 담긴 struct(하위 struct 포함)를 structTypeID와 함께 ext로 감싸서 인코딩
+// 타입 정보 없이 읽는 쪽(DecodeInterface, Skip)이 map과 struct를 구분할 수 있도록 한다.
+func (e *Encoder) encodeStructExt(strct reflect.Value, structFields *fields) error {
+	return e.encodeExtValue(typeIDExtID, func() error {
+		if err := e.EncodeUint(uint64(structTypeID)); err != nil {
+			return err
+		}
+		return e.encodeStructMap(strct, structFields)
+	})
+}
+
+// encodeStructMap : map 헤더, 필드명 크기 플래그, 필드 순서로 struct를 인코딩
+func (e *Encoder) encodeStructMap(strct reflect.Value, structFields *fields) error {
+	defer e.releaseFields(len(e.omitFields))
 	fields := structFields.OmitEmpty(e, strct)
+	names := e.flags&fieldNamesFlag != 0
+	ufs := structFields.unknownFields(strct, names)
 
 	// logc.Trace().Msgf(" <<<<<<<<<<<<<<<< encodeStruct  %s  >>>>>>>>>>>>>>>>>>>> %d/%d", strct.Type().Name(), len(fields), len(structFields.List))
 
 	// logc.Trace().Msgf("getEncoder fields length: %d, struct %s", len(fields), strct.Type().Name())
 
 	// map length
-	if err := e.encodeMapLen(len(fields)); err != nil {
+	if err := e.encodeMapLen(len(fields) + len(ufs)); err != nil {
 		return err
 	}
 
+	if names {
+		if err := e.encodeStructFieldNames(strct, fields); err != nil {
+			return err
+		}
+		return e.encodeUnknownFields(ufs, FieldNameSizeFlagString)
+	}
+
 	// field hashcode length
 	// 🔴CAUTION: msgpack에 없는 포맷
-	fieldLen, err := e.encodeFieldLen(fields)
+	fieldLen, err := e.encodeFieldLen(fields, ufs)
 	if err != nil {
 		return err
 	}
 
 	for _, f := range fields {
-		if err := f.encodeFieldName(e, fieldLen); err != nil {
+		width := fieldLen
+		if width == FieldNameSizeFlagMixed {
+			width = f.fieldName.size
+		}
+		if err := f.encodeFieldName(e, width); err != nil {
 			return err
 		}
 		// beforeLen := e.w.Len()
 		if err := f.EncodeValue(e, strct); err != nil {
-			return err
+			return e.fieldError(err, strct.Type(), f)
 		}
 		// encodedSize := e.w.Len() - beforeLen
 		// logc.Info().Msgf("%s(%b) => %d bytes [Total: %d]", f.fieldName.name, f.fieldName.hash32, encodedSize, e.w.Len())
 	}
 	// logc.Trace().Msgf(" <<<<<<<<<<<<<<<< ended %s  >>>>>>>>>>>>>>>>>>>>", strct.Type().Name())
 
+	return e.encodeUnknownFields(ufs, fieldLen)
+}
+
+// releaseFields : OmitEmpty가 쌓은 필드를 n개로 되돌림
+func (e *Encoder) releaseFields(n int) {
+	clear(e.omitFields[n:])
+	e.omitFields = e.omitFields[:n]
+}
+
+// encodeStructFieldNames : 필드명을 문자열로 기록 (UseFieldNames)
+func (e *Encoder) encodeStructFieldNames(strct reflect.Value, fields []*Field) error {
+	if err := e.writeCode(byte(FieldNameSizeFlagString)); err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		if err := e.EncodeString(f.fieldName.name); err != nil {
+			return err
+		}
+		if err := f.EncodeValue(e, strct); err != nil {
+			return e.fieldError(err, strct.Type(), f)
+		}
+	}
+	return nil
+}
+
+// encodeStructValueAsArray : 필드명 없이 필드 값만 선언 순서대로 배열로 기록 (omitempty 무시)
+func encodeStructValueAsArray(e *Encoder, strct reflect.Value, fields []*Field) error {
+	if err := e.encodeArrayLen(len(fields)); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if err := f.EncodeValue(e, strct); err != nil {
+			return e.fieldError(err, strct.Type(), f)
+		}
+	}
 	return nil
 }
 
@@ -198,8 +412,8 @@ func (e *Encoder) EncodeMapSorted(m map[string]interface{}) error {
 		if err := e.EncodeString(k); err != nil {
 			return err
 		}
-		if err := e.Encode(m[k]); err != nil {
-			return err
+		if err := e.encodeInterface(m[k]); err != nil {
+			return e.elemError(err, keySegment(reflect.ValueOf(k)))
 		}
 	}
 
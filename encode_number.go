@@ -27,6 +27,31 @@ func (e *Encoder) EncodeInt(n int64) error {
 	}
 	return e.EncodeInt64(n)
 }
+func (e *Encoder) encodeInt8Cond(n int8) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeInt(int64(n))
+	}
+	return e.EncodeInt8(n)
+}
+func (e *Encoder) encodeInt16Cond(n int16) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeInt(int64(n))
+	}
+	return e.EncodeInt16(n)
+}
+func (e *Encoder) encodeInt32Cond(n int32) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeInt(int64(n))
+	}
+	return e.EncodeInt32(n)
+}
+func (e *Encoder) encodeInt64Cond(n int64) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeInt(n)
+	}
+	return e.EncodeInt64(n)
+}
+
 func (e *Encoder) EncodeInt8(n int8) error {
 	return e.write1(Int8, uint8(n)) // 2 bytes (1+1)
 }
@@ -58,6 +83,31 @@ func (e *Encoder) EncodeUint(n uint64) error {
 	}
 	return e.EncodeUint64(n)
 }
+func (e *Encoder) encodeUint8Cond(n uint8) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeUint(uint64(n))
+	}
+	return e.EncodeUint8(n)
+}
+func (e *Encoder) encodeUint16Cond(n uint16) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeUint(uint64(n))
+	}
+	return e.EncodeUint16(n)
+}
+func (e *Encoder) encodeUint32Cond(n uint32) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeUint(uint64(n))
+	}
+	return e.EncodeUint32(n)
+}
+func (e *Encoder) encodeUint64Cond(n uint64) error {
+	if e.flags&useCompactIntsFlag != 0 {
+		return e.EncodeUint(n)
+	}
+	return e.EncodeUint64(n)
+}
+
 func (e *Encoder) EncodeUint8(n uint8) error {
 	return e.write1(Uint8, n) // 2 bytes (1+1)
 }
@@ -73,7 +123,7 @@ func (e *Encoder) EncodeUint64(n uint64) error {
 
 func (e *Encoder) EncodeFloat32(n float32) error {
 	if e.flags&useCompactFloatsFlag != 0 {
-		if float32(int64(n)) == n {
+		if float32(int64(n)) == n && !isNegativeZero(float64(n)) {
 			return e.EncodeInt(int64(n))
 		}
 	}
@@ -85,13 +135,22 @@ func (e *Encoder) EncodeFloat64(n float64) error {
 		// If n is NaN then it never compares true with any other value
 		// If n is Inf then it doesn't convert from int64 back to +/-Inf
 		// In both cases the comparison works.
-		if float64(int64(n)) == n {
+		if float64(int64(n)) == n && !isNegativeZero(n) {
 			return e.EncodeInt(int64(n))
 		}
+		// float32로 손실 없이 변환되는 값은 Float(5B)로 기록
+		if f32 := float32(n); float64(f32) == n {
+			return e.write4(Float, math.Float32bits(f32))
+		}
 	}
 	return e.write8(Double, math.Float64bits(n))
 }
 
+// isNegativeZero : -0.0은 정수로 바꾸면 부호가 사라지므로 실수로 기록
+func isNegativeZero(n float64) bool {
+	return n == 0 && math.Signbit(n)
+}
+
 // Int format family stores an integer in 1, 2, 3, 5, or 9 bytes.
 func (e *Encoder) write1(code byte, n uint8) error {
 	e.buf = e.buf[:2]
@@ -141,37 +200,37 @@ func encodeIntValue(e *Encoder, v reflect.Value) error {
 	return e.EncodeInt(v.Int())
 }
 
-// func encodeUint8CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeUint8Cond(uint8(v.Uint()))
-// }
+func encodeUint8CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeUint8Cond(uint8(v.Uint()))
+}
 
-// func encodeUint16CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeUint16Cond(uint16(v.Uint()))
-// }
+func encodeUint16CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeUint16Cond(uint16(v.Uint()))
+}
 
-// func encodeUint32CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeUint32Cond(uint32(v.Uint()))
-// }
+func encodeUint32CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeUint32Cond(uint32(v.Uint()))
+}
 
-// func encodeUint64CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeUint64Cond(v.Uint())
-// }
+func encodeUint64CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeUint64Cond(v.Uint())
+}
 
-// func encodeInt8CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeInt8Cond(int8(v.Int()))
-// }
+func encodeInt8CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeInt8Cond(int8(v.Int()))
+}
 
-// func encodeInt16CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeInt16Cond(int16(v.Int()))
-// }
+func encodeInt16CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeInt16Cond(int16(v.Int()))
+}
 
-// func encodeInt32CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeInt32Cond(int32(v.Int()))
-// }
+func encodeInt32CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeInt32Cond(int32(v.Int()))
+}
 
-// func encodeInt64CondValue(e *Encoder, v reflect.Value) error {
-// 	return e.encodeInt64Cond(v.Int())
-// }
+func encodeInt64CondValue(e *Encoder, v reflect.Value) error {
+	return e.encodeInt64Cond(v.Int())
+}
 
 func encodeFloat32Value(e *Encoder, v reflect.Value) error {
 	return e.EncodeFloat32(float32(v.Float()))
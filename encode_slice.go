@@ -87,7 +87,7 @@ func encodeArrayValue(e *Encoder, v reflect.Value) error {
 	}
 	for i := 0; i < l; i++ {
 		if err := e.EncodeValue(v.Index(i)); err != nil {
-			return err
+			return e.elemError(err, indexSegment(i))
 		}
 	}
 	return nil
@@ -0,0 +1,441 @@
+package hpack_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+// f2, f50만 2B, 나머지는 1B
+type mixedWidths struct {
+	F2  int `msgpack:"f2"`
+	F50 int `msgpack:"f50"`
+	A   int `msgpack:"a"`
+	B   int `msgpack:"b"`
+	C   int `msgpack:"c"`
+	D   int `msgpack:"d"`
+	E   int `msgpack:"e"`
+}
+
+type mixedPinned struct {
+	Gold int `msgpack:"gold,hash=0x12345"`
+	A    int `msgpack:"a"`
+	B    int `msgpack:"b"`
+	C    int `msgpack:"c"`
+	D    int `msgpack:"d"`
+	E    int `msgpack:"e"`
+}
+
+type mixedTail struct {
+	E int `msgpack:"e"`
+}
+
+func TestMixedWidths(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		flag hpack.FieldNameSizeFlag
+	}{
+		{"2B fields", &mixedWidths{F2: 1, F50: 2, A: 3, B: 4, C: 5, D: 6, E: 7}, hpack.FieldNameSizeFlagMixed},
+		{"4B pinned", &mixedPinned{Gold: 1, A: 2, B: 3, C: 4, D: 5, E: 6}, hpack.FieldNameSizeFlagMixed},
+		{"all 2B", &vWidened{F2: 1, F50: 2}, hpack.FieldNameSizeFlag2Byte},
+		{"all 1B", &vClean{ID: 1, Name: "kim"}, hpack.FieldNameSizeFlag1Byte},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hpack.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// fixmap 헤더 다음이 크기 플래그
+			if hpack.FieldNameSizeFlag(b[1]) != tt.flag {
+				t.Fatalf("header = % x, want flag %s", b[:2], tt.flag.ToString())
+			}
+
+			out := reflect.New(reflect.TypeOf(tt.in).Elem())
+			if err := hpack.Unmarshal(b, out.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(out.Interface(), tt.in) {
+				t.Fatalf("got %+v, want %+v", out.Interface(), tt.in)
+			}
+
+			v := decodeAny(t, tt.in, nil)
+			if m, ok := v.(map[uint32]interface{}); !ok || len(m) != reflect.ValueOf(tt.in).Elem().NumField() {
+				t.Fatalf("DecodeInterface = %#v", v)
+			}
+		})
+	}
+
+	// 필드별 크기가 다른 필드들을 건너뛰고 마지막 필드를 읽는다.
+	b, err := hpack.Marshal(&mixedWidths{E: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tail mixedTail
+	if err := hpack.Unmarshal(b, &tail); err != nil || tail.E != 7 {
+		t.Fatalf("got %+v, err %v", tail, err)
+	}
+}
+
+func encode(t *testing.T, v interface{}, setup func(*hpack.Encoder)) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	if setup != nil {
+		setup(enc)
+	}
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type namedInner struct {
+	Level int `msgpack:"level"`
+}
+
+type namedOuter struct {
+	ID    int          `msgpack:"id"`
+	Name  string       `msgpack:"name"`
+	Inner namedInner   `msgpack:"inner"`
+	List  []namedInner `msgpack:"list"`
+}
+
+func TestUseFieldNames(t *testing.T) {
+	in := namedOuter{ID: 1, Name: "kim", Inner: namedInner{Level: 2}, List: []namedInner{{Level: 3}}}
+	b := encode(t, &in, func(enc *hpack.Encoder) { enc.UseFieldNames(true) })
+
+	if hpack.FieldNameSizeFlag(b[1]) != hpack.FieldNameSizeFlagString {
+		t.Fatalf("header = % x", b[:2])
+	}
+	for _, name := range []string{"id", "name", "inner", "list", "level"} {
+		if !bytes.Contains(b, []byte(name)) {
+			t.Fatalf("%q not in % x", name, b)
+		}
+	}
+
+	// 디코더는 옵션 없이 두 형식을 모두 읽는다.
+	var out namedOuter
+	if err := hpack.Unmarshal(b, &out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+	if err := hpack.UnmarshalStrict(b, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	v := decodeAny(t, &in, func(enc *hpack.Encoder) { enc.UseFieldNames(true) })
+	m, ok := v.(map[string]interface{})
+	if !ok || m["name"] != "kim" || m["inner"].(map[string]interface{})["level"] != int8(2) {
+		t.Fatalf("DecodeInterface = %#v", v)
+	}
+
+	// 이름을 모르는 필드는 건너뛴다.
+	var tail struct {
+		Inner namedInner   `msgpack:"inner"`
+		List  []namedInner `msgpack:"list"`
+	}
+	if err := hpack.Unmarshal(b, &tail); err != nil || len(tail.List) != 1 || tail.List[0].Level != 3 {
+		t.Fatalf("got %+v, err %v", tail, err)
+	}
+}
+
+type sortKey struct{ id int }
+
+func (k sortKey) MarshalText() ([]byte, error) { return []byte{byte('z' - k.id)}, nil }
+
+// sortedMap : 키를 순서대로 기록한 fixmap
+func sortedMap(t *testing.T, kvs ...interface{}) []byte {
+	t.Helper()
+	b := []byte{hpack.FixedMapLow | byte(len(kvs)/2)}
+	for _, kv := range kvs {
+		vb, err := hpack.Marshal(kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, vb...)
+	}
+	return b
+}
+
+func TestSortMapKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want []byte
+	}{
+		{"string", map[string]int{"b": 2, "a": 1, "c": 3}, sortedMap(t, "a", 1, "b", 2, "c", 3)},
+		{"string string", map[string]string{"b": "2", "a": "1"}, sortedMap(t, "a", "1", "b", "2")},
+		{"string any", map[string]interface{}{"b": 2, "a": "1"}, sortedMap(t, "a", "1", "b", 2)},
+		{"int", map[int]string{3: "c", -1: "a", 2: "b"}, sortedMap(t, -1, "a", 2, "b", 3, "c")},
+		{"uint", map[uint16]bool{300: true, 1: false}, sortedMap(t, uint16(1), false, uint16(300), true)},
+		{"float", map[float64]int{2.5: 2, -1: 1}, sortedMap(t, -1.0, 1, 2.5, 2)},
+		{"bool", map[bool]int{true: 1, false: 0}, sortedMap(t, false, 0, true, 1)},
+		{"text", map[sortKey]int{{1}: 1, {2}: 2}, sortedMap(t, sortKey{2}, 2, sortKey{1}, 1)},
+	}
+	sorted := func(enc *hpack.Encoder) { enc.SetSortMapKeys(true) }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				if got := encode(t, tt.in, sorted); !bytes.Equal(got, tt.want) {
+					t.Fatalf("got % x, want % x", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// struct 필드의 map도 정렬된다.
+	in := struct {
+		M map[string]int `msgpack:"m"`
+	}{M: map[string]int{"c": 3, "b": 2, "a": 1}}
+	if got := encode(t, &in, sorted); !bytes.HasSuffix(got, sortedMap(t, "a", 1, "b", 2, "c", 3)) {
+		t.Fatalf("got % x", got)
+	}
+
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(map[[2]int]int{{1, 2}: 1}); err == nil {
+		t.Fatal("array key: want error")
+	}
+}
+
+type dPos struct {
+	_hpack struct{} `msgpack:",as_array"`
+	X, Y   float32
+}
+
+type dPosAppend struct {
+	_hpack  struct{} `msgpack:",as_array"`
+	X, Y, Z float32
+}
+
+func TestArrayEncodedStructs(t *testing.T) {
+	b := encode(t, &dPos{X: 1, Y: 2}, nil)
+	if b[0] != hpack.FixedArrayLow|2 {
+		t.Fatalf("as_array = % x, want fixarray", b)
+	}
+	var pos dPos
+	if err := hpack.Unmarshal(b, &pos); err != nil || pos.X != 1 || pos.Y != 2 {
+		t.Fatalf("got %+v, err %v", pos, err)
+	}
+
+	// 인코더 전체 옵션
+	arrays := func(enc *hpack.Encoder) { enc.UseArrayEncodedStructs(true) }
+	in := namedOuter{ID: 1, Name: "kim", Inner: namedInner{Level: 2}, List: []namedInner{{Level: 3}}}
+	b = encode(t, &in, arrays)
+	if b[0] != hpack.FixedArrayLow|4 || b[6] != hpack.FixedArrayLow|1 {
+		t.Fatalf("UseArrayEncodedStructs = % x", b)
+	}
+	var out namedOuter
+	if err := hpack.Unmarshal(b, &out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+
+	// omitempty는 무시된다.
+	b = encode(t, &struct {
+		A int `msgpack:"a,omitempty"`
+		B int `msgpack:"b"`
+	}{B: 1}, arrays)
+	if !bytes.Equal(b, []byte{hpack.FixedArrayLow | 2, 0, 1}) {
+		t.Fatalf("omitempty = % x", b)
+	}
+}
+
+func TestArrayEncodedStructsAppend(t *testing.T) {
+	long := encode(t, &dPosAppend{X: 1, Y: 2, Z: 3}, nil)
+	short := encode(t, &dPos{X: 1, Y: 2}, nil)
+
+	// 긴 배열의 나머지 값은 건너뛴다.
+	var pos dPos
+	if err := hpack.Unmarshal(long, &pos); err != nil || pos.X != 1 || pos.Y != 2 {
+		t.Fatalf("got %+v, err %v", pos, err)
+	}
+	if err := decode(long, &pos, func(dec *hpack.Decoder) { dec.DisallowUnknownFields(true) }); err == nil {
+		t.Fatal("DisallowUnknownFields: want error")
+	}
+
+	// 짧은 배열은 앞쪽 필드만 채운다.
+	pa := dPosAppend{Z: 9}
+	if err := hpack.Unmarshal(short, &pa); err != nil || pa.X != 1 || pa.Y != 2 {
+		t.Fatalf("got %+v, err %v", pa, err)
+	}
+}
+
+func TestCompactNumbers(t *testing.T) {
+	wideInts := func(enc *hpack.Encoder) { enc.UseCompactInts(false) }
+	compactFloats := func(enc *hpack.Encoder) { enc.UseCompactFloats(true) }
+	tests := []struct {
+		name  string
+		in    interface{}
+		setup func(*hpack.Encoder)
+		want  []byte
+	}{
+		{"int64 compact", int64(1), nil, []byte{1}},
+		{"int64 wide", int64(1), wideInts, []byte{hpack.Int64, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{"uint16 wide", uint16(5), wideInts, []byte{hpack.Uint16, 0, 5}},
+		{"int8 wide", int8(-1), wideInts, []byte{hpack.Int8, 0xff}},
+		{"int stays compact", 300, wideInts, []byte{hpack.Uint16, 0x01, 0x2c}},
+		{"float64", 2.0, nil, []byte{hpack.Double, 0x40, 0, 0, 0, 0, 0, 0, 0}},
+		{"float64 integral", 2.0, compactFloats, []byte{2}},
+		{"float64 negative integral", -3.0, compactFloats, []byte{0xfd}},
+		{"float64 as float32", 1.5, compactFloats, []byte{hpack.Float, 0x3f, 0xc0, 0, 0}},
+		{"float64 exact", 0.1, compactFloats, []byte{hpack.Double, 0x3f, 0xb9, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{"negative zero", math.Copysign(0, -1), compactFloats, []byte{hpack.Float, 0x80, 0, 0, 0}},
+		{"float32 integral", float32(3), compactFloats, []byte{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encode(t, tt.in, tt.setup); !bytes.Equal(got, tt.want) {
+				t.Fatalf("got % x, want % x", got, tt.want)
+			}
+		})
+	}
+
+	b := encode(t, math.NaN(), compactFloats)
+	var nan float64
+	if err := hpack.Unmarshal(b, &nan); err != nil || !math.IsNaN(nan) {
+		t.Fatalf("NaN: got %v (% x), err %v", nan, b, err)
+	}
+}
+
+func TestDecodeNumbersIntoFloats(t *testing.T) {
+	type floats struct {
+		F64 float64 `msgpack:"f64"`
+		F32 float32 `msgpack:"f32"`
+	}
+	in := floats{F64: 7, F32: -2}
+	b := encode(t, &in, func(enc *hpack.Encoder) { enc.UseCompactFloats(true) })
+
+	var out floats
+	if err := hpack.Unmarshal(b, &out); err != nil || out != in {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+
+	for _, v := range []interface{}{uint8(200), int16(-300), float32(1.5), uint64(1 << 40)} {
+		var f float64
+		if err := hpack.Unmarshal(encode(t, v, func(enc *hpack.Encoder) { enc.UseCompactInts(false) }), &f); err != nil ||
+			f != reflect.ValueOf(v).Convert(reflect.TypeOf(f)).Float() {
+			t.Fatalf("%T(%v): got %v, err %v", v, v, f, err)
+		}
+	}
+}
+
+type internTagged struct {
+	A    string         `msgpack:"a,intern"`
+	B    string         `msgpack:"b,intern"`
+	List []string       `msgpack:"list,intern"`
+	M    map[string]int `msgpack:"m,intern"`
+	Any  interface{}    `msgpack:"any,intern"`
+	Raw  string         `msgpack:"raw"`
+}
+
+func TestInternedStrings(t *testing.T) {
+	in := internTagged{
+		A: "hello", B: "hello", List: []string{"hello", "hi", "hi"}, M: map[string]int{"hello": 1}, Any: "hello", Raw: "hello",
+	}
+	b := encode(t, &in, nil)
+	// 첫 번째만 문자열로 기록하고, 사전에 있는 문자열은 태그가 없는 필드(Raw)도 인덱스로 기록한다.
+	if n := bytes.Count(b, []byte("hello")); n != 1 {
+		t.Fatalf("hello written %d times: % x", n, b)
+	}
+	var out internTagged
+	if err := hpack.Unmarshal(b, &out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+
+	// 전체 옵션: 3바이트 이상 문자열만 인터닝한다.
+	words := []string{"hello", "hi", "hello", "hi", "world", "world"}
+	b = encode(t, words, func(enc *hpack.Encoder) { enc.UseInternedStrings(true) })
+	if bytes.Count(b, []byte("hello")) != 1 || bytes.Count(b, []byte("world")) != 1 || bytes.Count(b, []byte("hi")) != 2 {
+		t.Fatalf("encoded % x", b)
+	}
+	var got []string
+	if err := decode(b, &got, func(dec *hpack.Decoder) { dec.UseInternedStrings(true) }); err != nil || !reflect.DeepEqual(got, words) {
+		t.Fatalf("got %v, err %v", got, err)
+	}
+}
+
+func TestInternedStringsSharedDict(t *testing.T) {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	enc.ResetDict(&buf, map[string]int{"hello": 0})
+	enc.UseInternedStrings(true)
+	if err := enc.Encode([]string{"hello", "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("hello")) {
+		t.Fatalf("encoded % x, want dictionary index", buf.Bytes())
+	}
+
+	dec := hpack.NewDecoder(nil)
+	dec.ResetDict(bytes.NewReader(buf.Bytes()), []string{"hello"})
+	dec.UseInternedStrings(true)
+	var got []string
+	if err := dec.Decode(&got); err != nil || !reflect.DeepEqual(got, []string{"hello", "hello"}) {
+		t.Fatalf("got %v, err %v", got, err)
+	}
+
+	// 사전이 없으면 인덱스를 찾을 수 없다.
+	if err := decode(buf.Bytes(), &got, func(dec *hpack.Decoder) { dec.UseInternedStrings(true) }); err == nil {
+		t.Fatal("without dict: want error")
+	}
+}
+
+type omitInner struct {
+	Level int `msgpack:"level"`
+}
+
+type omitField struct {
+	A int    `msgpack:"a,omitempty"`
+	B string `msgpack:"b"`
+}
+
+type omitStruct struct {
+	_hpack    struct{} `hpack:",omitempty"`
+	omitInner `msgpack:",inline"`
+	HP        int            `msgpack:"hp"`
+	Buffs     []string       `msgpack:"buffs"`
+	M         map[string]int `msgpack:"m"`
+	P         *omitInner     `msgpack:"p"`
+}
+
+type omitMsgpack struct {
+	_msgpack struct{} `msgpack:",omitempty"`
+	A        int      `msgpack:"a"`
+	B        int      `msgpack:"b"`
+}
+
+func TestOmitEmpty(t *testing.T) {
+	omitAll := func(enc *hpack.Encoder) { enc.SetOmitEmpty(true) }
+	tests := []struct {
+		name  string
+		in    interface{}
+		setup func(*hpack.Encoder)
+		keys  int
+	}{
+		{"field", &omitField{}, nil, 1},
+		{"field set", &omitField{A: 1}, nil, 2},
+		{"struct", &omitStruct{}, nil, 0},
+		{"struct set", &omitStruct{omitInner: omitInner{Level: 1}, HP: 1, P: &omitInner{}}, nil, 3},
+		{"_msgpack", &omitMsgpack{B: 1}, nil, 1},
+		{"encoder", &namedOuter{ID: 1}, omitAll, 1},
+		{"encoder off", &namedOuter{ID: 1}, nil, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := encode(t, tt.in, tt.setup)
+			if m := decodeAny(t, tt.in, tt.setup).(map[uint32]interface{}); len(m) != tt.keys {
+				t.Fatalf("keys = %v, want %d", m, tt.keys)
+			}
+
+			out := reflect.New(reflect.TypeOf(tt.in).Elem())
+			if err := hpack.Unmarshal(b, out.Interface()); err != nil || !reflect.DeepEqual(out.Interface(), tt.in) {
+				t.Fatalf("got %+v, err %v", out.Interface(), err)
+			}
+		})
+	}
+}
@@ -10,25 +10,17 @@ var valueEncoders []encoderFunc
 
 func init() {
 	valueEncoders = []encoderFunc{
-		reflect.Bool:  encodeBoolValue,
-		reflect.Int:   encodeIntValue,
-		reflect.Int8:  encodeIntValue,
-		reflect.Int16: encodeIntValue,
-		reflect.Int32: encodeIntValue,
-		reflect.Int64: encodeIntValue,
-		// reflect.Int8:          encodeInt8CondValue,
-		// reflect.Int16:         encodeInt16CondValue,
-		// reflect.Int32:         encodeInt32CondValue,
-		// reflect.Int64:         encodeInt64CondValue,
-		reflect.Uint:   encodeUintValue,
-		reflect.Uint8:  encodeUintValue,
-		reflect.Uint16: encodeUintValue,
-		reflect.Uint32: encodeUintValue,
-		reflect.Uint64: encodeUintValue,
-		// reflect.Uint8:         encodeUint8CondValue,
-		// reflect.Uint16:        encodeUint16CondValue,
-		// reflect.Uint32:        encodeUint32CondValue,
-		// reflect.Uint64:        encodeUint64CondValue,
+		reflect.Bool:          encodeBoolValue,
+		reflect.Int:           encodeIntValue,
+		reflect.Int8:          encodeInt8CondValue,
+		reflect.Int16:         encodeInt16CondValue,
+		reflect.Int32:         encodeInt32CondValue,
+		reflect.Int64:         encodeInt64CondValue,
+		reflect.Uint:          encodeUintValue,
+		reflect.Uint8:         encodeUint8CondValue,
+		reflect.Uint16:        encodeUint16CondValue,
+		reflect.Uint32:        encodeUint32CondValue,
+		reflect.Uint64:        encodeUint64CondValue,
 		reflect.Float32:       encodeFloat32Value,
 		reflect.Float64:       encodeFloat64Value,
 		reflect.Complex64:     encodeUnsupportedValue,
@@ -169,6 +161,11 @@ func encodeByteSliceValue(e *Encoder, v reflect.Value) error {
 }
 
 func encodeStringSliceValue(e *Encoder, v reflect.Value) error {
+	// 주소를 얻을 수 있으면 포인터로 꺼내 슬라이스를 interface에 담는 할당을 피한다.
+	if v.CanAddr() {
+		ptr := v.Addr().Convert(sliceStringPtrType).Interface().(*[]string)
+		return e.encodeStringSlice(*ptr)
+	}
 	ss := v.Convert(stringSliceType).Interface().([]string)
 	return e.encodeStringSlice(ss)
 }
@@ -199,7 +196,7 @@ func encodeInterfaceValue(e *Encoder, v reflect.Value) error {
 	if v.IsNil() {
 		return e.EncodeNil()
 	}
-	return e.EncodeValue(v.Elem())
+	return e.encodeInterfaceElem(v.Elem())
 }
 
 func nilable(kind reflect.Kind) bool {
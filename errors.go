@@ -0,0 +1,136 @@
+package hpack
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Error : 인코딩/디코딩 에러와 에러가 발생한 위치. errors.As로 꺼낼 수 있다.
+//
+// struct 필드, 슬라이스 원소, map 값 안에서 발생한 에러와 디코딩 중 잘못된 코드를 읽은 에러가 *Error로 반환된다.
+type Error struct {
+	Path     string            // Go 값 경로 (예: LoginReq.Devices[3].Token)
+	Hash     uint32            // 에러가 발생한 필드의 해시
+	Width    FieldNameSizeFlag // 에러가 발생한 필드의 해시 크기
+	Offset   int64             // 에러가 발생한 입력의 바이트 위치. 인코딩 에러는 -1
+	Expected string            // 잘못된 코드를 읽은 경우 디코딩하려던 값 (예: "bool")
+	Code     byte              // 잘못된 코드를 읽은 경우 읽은 코드
+	Err      error
+
+	hasField bool
+	root     int // Path 앞에 붙은 struct 타입명의 길이. 바깥 경로를 붙일 때 떼어낸다.
+}
+
+func (e *Error) Error() string {
+	var loc []string
+	if e.Path != "" {
+		loc = append(loc, "path="+e.Path)
+	}
+	if e.hasField {
+		loc = append(loc, fmt.Sprintf("hash=%#x(%s)", e.Hash, e.Width.ToString()))
+	}
+	if e.Offset >= 0 {
+		loc = append(loc, "offset="+strconv.FormatInt(e.Offset, 10))
+	}
+	if len(loc) == 0 {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + " (" + strings.Join(loc, " ") + ")"
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError : err가 *Error이면 그대로, 아니면 offset에서 발생한 에러로 감싼다.
+// 잘못된 코드를 읽은 에러는 마지막으로 읽은 바이트(offset-1)를 위치로 한다.
+func newError(err error, offset int64) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+
+	e := &Error{Err: err, Offset: offset}
+	if ce, ok := err.(unexpectedCodeError); ok {
+		e.Expected = ce.hint
+		e.Code = ce.code
+		if offset > 0 {
+			e.Offset = offset - 1
+		}
+	}
+	return e
+}
+
+// withField : 가장 안쪽 필드의 해시를 기록
+func (e *Error) withField(fname FieldName) *Error {
+	if !e.hasField {
+		e.Hash = fname.hash32
+		e.Width = fname.size
+		e.hasField = true
+	}
+	return e
+}
+
+// withPath : 경로 앞에 seg를 붙임. 안쪽 struct의 타입명은 떼어낸다.
+func (e *Error) withPath(seg string) *Error {
+	e.Path = seg + e.Path[e.root:]
+	e.root = 0
+	return e
+}
+
+// withType : 경로 앞에 struct 타입명을 붙임. 바깥 경로가 붙으면 떼어낸다.
+func (e *Error) withType(typ reflect.Type) *Error {
+	if name := typ.Name(); name != "" && e.root == 0 {
+		e.Path = name + e.Path
+		e.root = len(name)
+	}
+	return e
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+func keySegment(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return "[" + strconv.Quote(k.String()) + "]"
+	}
+	return fmt.Sprintf("[%v]", k)
+}
+
+// fieldError : struct typ의 필드 f에서 발생한 에러
+func fieldError(err error, offset int64, typ reflect.Type, f *Field) error {
+	return newError(err, offset).withField(f.fieldName).withPath("." + f.goName).withType(typ)
+}
+
+// elemError : 슬라이스 원소, map 값(seg)에서 발생한 에러
+func elemError(err error, offset int64, seg string) error {
+	return newError(err, offset).withPath(seg)
+}
+
+func (d *Decoder) fieldError(err error, typ reflect.Type, f *Field) error {
+	return fieldError(err, d.read, typ, f)
+}
+
+func (d *Decoder) elemError(err error, seg string) error {
+	return elemError(err, d.read, seg)
+}
+
+func (e *Encoder) fieldError(err error, typ reflect.Type, f *Field) error {
+	return fieldError(err, -1, typ, f)
+}
+
+func (e *Encoder) elemError(err error, seg string) error {
+	return elemError(err, -1, seg)
+}
+
+// valueError : Decode의 최상위 값(typ)에서 발생한 에러
+// 안쪽 위치가 없는 에러는 잘못된 코드를 읽거나 UseStrict에서 거부한 경우에만 *Error로 감싼다. (io.EOF 등은 그대로 반환)
+func (d *Decoder) valueError(err error, typ reflect.Type) error {
+	switch err.(type) {
+	case *Error, unexpectedCodeError, *StrictError:
+		return newError(err, d.read).withType(typ)
+	}
+	return err
+}
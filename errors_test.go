@@ -0,0 +1,85 @@
+package hpack_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type errDevice struct {
+	Token string `msgpack:"token"`
+}
+
+type errDeviceBad struct {
+	Token int `msgpack:"token"`
+}
+
+type errPacket struct {
+	Devices []errDevice          `msgpack:"devices"`
+	ByName  map[string]errDevice `msgpack:"byName"`
+}
+
+type errPacketBad struct {
+	Devices []interface{}           `msgpack:"devices"`
+	ByName  map[string]errDeviceBad `msgpack:"byName"`
+}
+
+var errMarshal = errors.New("marshal failed")
+
+type errMarshaler struct{}
+
+func (errMarshaler) MarshalMsgpack() ([]byte, error) { return nil, errMarshal }
+
+func TestDecodeError(t *testing.T) {
+	token := schemaOf(t, errDevice{}).Types[0].Field("token")
+	tests := []struct {
+		name string
+		in   errPacketBad
+		path string
+	}{
+		{"slice", errPacketBad{Devices: []interface{}{errDevice{Token: "a"}, errDeviceBad{Token: 7}}}, "errPacket.Devices[1].Token"},
+		{"map", errPacketBad{ByName: map[string]errDeviceBad{"pc": {Token: 7}}}, `errPacket.ByName["pc"].Token`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hpack.Marshal(&tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = hpack.Unmarshal(b, &errPacket{})
+			var herr *hpack.Error
+			if !errors.As(err, &herr) {
+				t.Fatalf("got %v, want *hpack.Error", err)
+			}
+			if herr.Path != tt.path || herr.Hash != token.Hash || herr.Width != token.Size {
+				t.Fatalf("got %+v, want path %s", herr, tt.path)
+			}
+			if herr.Code != 7 || herr.Expected == "" || b[herr.Offset] != herr.Code {
+				t.Fatalf("code %#x at offset %d (% x), expected %q", herr.Code, herr.Offset, b, herr.Expected)
+			}
+		})
+	}
+
+	// 입력이 끝난 경우는 감싸지 않는다.
+	if err := hpack.Unmarshal(nil, &errPacket{}); !errors.Is(err, io.EOF) {
+		t.Fatalf("empty input: got %v", err)
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	in := struct {
+		List []interface{} `msgpack:"list"`
+	}{List: []interface{}{1, errMarshaler{}}}
+
+	_, err := hpack.Marshal(&in)
+	var herr *hpack.Error
+	if !errors.As(err, &herr) || !errors.Is(err, errMarshal) {
+		t.Fatalf("got %v, want *hpack.Error wrapping errMarshal", err)
+	}
+	if herr.Path != ".List[1]" || herr.Offset != -1 {
+		t.Fatalf("got %+v", herr)
+	}
+}
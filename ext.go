@@ -44,6 +44,7 @@ func RegisterExtEncoder(
 	value interface{},
 	encoder func(enc *Encoder, v reflect.Value) ([]byte, error),
 ) {
+	checkExtID(extID)
 	unregisterExtEncoder(extID)
 
 	typ := reflect.TypeOf(value)
@@ -55,6 +56,13 @@ func RegisterExtEncoder(
 	}
 }
 
+// checkExtID : hpack이 사용하는 ext 타입(RegisterType)이면 panic
+func checkExtID(extID int8) {
+	if extID == typeIDExtID {
+		panic(fmt.Errorf("hpack: ext id=%d is reserved for RegisterType", extID))
+	}
+}
+
 func unregisterExtEncoder(extID int8) {
 	t, ok := typeEncMap.Load(extID)
 	if !ok {
@@ -107,6 +115,7 @@ func RegisterExtDecoder(
 	value interface{},
 	decoder func(dec *Decoder, v reflect.Value, extLen int) error,
 ) {
+	checkExtID(extID)
 	unregisterExtDecoder(extID)
 
 	typ := reflect.TypeOf(value)
@@ -229,18 +238,35 @@ func (d *Decoder) parseExtLen(c byte) (int, error) {
 		return 8, nil
 	case msgpcode.FixExt16:
 		return 16, nil
+	}
+
+	var n int
+	switch c {
 	case msgpcode.Ext8:
-		n, err := d.uint8()
-		return int(n), err
+		v, err := d.uint8()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
 	case msgpcode.Ext16:
-		n, err := d.uint16()
-		return int(n), err
+		v, err := d.uint16()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
 	case msgpcode.Ext32:
-		n, err := d.uint32()
-		return int(n), err
+		v, err := d.uint32()
+		if err != nil {
+			return 0, err
+		}
+		n = int(v)
 	default:
-		return 0, fmt.Errorf("hpack: invalid code=%x decoding ext len", c)
+		return 0, unexpectedCodeError{code: c, hint: "ext len"}
 	}
+	if err := d.checkShortestLen(c, n); err != nil {
+		return 0, err
+	}
+	return n, d.checkExtLen(n)
 }
 
 func (d *Decoder) decodeInterfaceExt(c byte) (interface{}, error) {
@@ -248,6 +274,14 @@ func (d *Decoder) decodeInterfaceExt(c byte) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	return d.decodeExtInterface(extID, extLen)
+}
+
+// decodeExtInterface : ext 헤더 뒤의 payload를 extID에 맞는 값으로 디코딩
+func (d *Decoder) decodeExtInterface(extID int8, extLen int) (interface{}, error) {
+	if extID == typeIDExtID {
+		return d.decodeRegisteredValue(extLen)
+	}
 
 	info, ok := extTypes[extID]
 	if !ok {
@@ -255,7 +289,7 @@ func (d *Decoder) decodeInterfaceExt(c byte) (interface{}, error) {
 	}
 
 	v := d.newValue(info.Type).Elem()
-	if nilable(v.Kind()) && v.IsNil() {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
 		v.Set(d.newValue(info.Type.Elem()))
 	}
 
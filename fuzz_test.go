@@ -0,0 +1,214 @@
+package hpack_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type fuzzItem struct {
+	ID    uint32            `msgpack:"id"`
+	Name  string            `msgpack:"name,intern"`
+	Tags  []string          `msgpack:"tags"`
+	Attrs map[string]int    `msgpack:"attrs"`
+	Raw   []byte            `msgpack:"raw"`
+	Any   interface{}       `msgpack:"any"`
+	Next  *fuzzItem         `msgpack:"next"`
+	Pair  [2]int16          `msgpack:"pair"`
+	Opt   *float64          `msgpack:"opt,omitempty"`
+	At    time.Time         `msgpack:"at"`
+	Dur   time.Duration     `msgpack:"dur"`
+	Keys  map[int8]*float32 `msgpack:"keys"`
+}
+
+type fuzzBase struct {
+	Seq  int64 `msgpack:"seq"`
+	Flag bool  `msgpack:"flag"`
+}
+
+type fuzzArray struct {
+	_msgpack struct{} `msgpack:",as_array"`
+
+	A int8      `msgpack:"a"`
+	B *fuzzBase `msgpack:"b"`
+	C string    `msgpack:"c"`
+}
+
+// fuzzBadTag : 태그 오류가 있는 struct. 디코딩은 panic 없이 에러를 반환해야 한다.
+type fuzzBadTag struct {
+	A    int         `msgpack:"a,hash=zz"`
+	Base fuzzBase    `msgpack:"base"`
+	Dup  fuzzDupHash `msgpack:"dup,omitempty"`
+}
+
+type fuzzDupHash struct {
+	A int `msgpack:"a,hash=0x10"`
+	B int `msgpack:"b,hash=0x10"`
+}
+
+type fuzzHidden struct {
+	Secret string `msgpack:"secret"`
+}
+
+type fuzzPacket struct {
+	fuzzBase
+	*fuzzHidden
+	Items   []fuzzItem           `msgpack:"items"`
+	ByName  map[string]*fuzzItem `msgpack:"byName"`
+	Arr     fuzzArray            `msgpack:"arr"`
+	ArrPtr  **fuzzArray          `msgpack:"arrPtr"`
+	Payload fuzzPayload          `msgpack:"payload"`
+	Err     error                `msgpack:"err"`
+	Unknown hpack.UnknownFields  `msgpack:",unknown"`
+}
+
+type fuzzPayload interface{}
+
+type fuzzChat struct {
+	Text string `msgpack:"text"`
+}
+
+func init() {
+	hpack.RegisterType[*fuzzChat](1)
+	hpack.RegisterType[fuzzBase](2)
+}
+
+func fuzzSeeds(f *testing.F) {
+	opt := 1.5
+	arr := &fuzzArray{A: 1, B: &fuzzBase{Seq: 2}, C: "c"}
+	values := []interface{}{
+		nil, true, int8(-3), uint64(1 << 40), 1.25, "str", []byte{1, 2},
+		[]interface{}{1, "a", nil}, map[string]interface{}{"a": 1, "b": []int{1}},
+		map[int]string{1: "a"}, time.Unix(1700000000, 5),
+		&fuzzItem{ID: 1, Name: "n", Tags: []string{"t"}, Attrs: map[string]int{"k": 1}, Opt: &opt, Any: &fuzzChat{"hi"}},
+		&fuzzPacket{
+			fuzzBase: fuzzBase{Seq: 7},
+			Items:    []fuzzItem{{ID: 2, Next: &fuzzItem{ID: 3}}},
+			ByName:   map[string]*fuzzItem{"x": {ID: 4}},
+			Arr:      *arr,
+			ArrPtr:   &arr,
+			Payload:  fuzzBase{Seq: 9},
+		},
+		arr,
+	}
+
+	for _, v := range values {
+		b, err := hpack.Marshal(v)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+
+		var buf bytes.Buffer
+		enc := hpack.NewEncoder(&buf)
+		enc.UseFieldNames(true)
+		enc.UseInternedStrings(true)
+		if err := enc.Encode(v); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+}
+
+func TestFuzzSeeds(t *testing.T) {
+	// as_array seed는 필드명 없이 배열로 인코딩되어야 한다.
+	b, err := hpack.Marshal(&fuzzArray{A: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{hpack.FixedArrayLow | 3, 5, hpack.Nil, hpack.FixedStrLow}; !bytes.Equal(b, want) {
+		t.Fatalf("fuzzArray = % x, want % x", b, want)
+	}
+
+	// 태그 오류가 있는 대상은 인코딩/디코딩 모두 에러를 반환한다.
+	if _, err := hpack.Marshal(&fuzzBadTag{}); err == nil {
+		t.Fatal("Marshal(fuzzBadTag): no error")
+	}
+	if _, err := hpack.Marshal(&fuzzDupHash{}); err == nil {
+		t.Fatal("Marshal(fuzzDupHash): no error")
+	}
+	for _, data := range [][]byte{{hpack.FixedMapLow, 0}, {hpack.FixedArrayLow | 1, 1}} {
+		if err := hpack.Unmarshal(data, new(fuzzBadTag)); err == nil {
+			t.Fatalf("Unmarshal(% x, fuzzBadTag): no error", data)
+		}
+	}
+}
+
+// 임의의 입력은 에러를 반환할 수 있지만 panic이 발생하면 안 된다.
+func fuzzDecode(data []byte, v interface{}, setup func(*hpack.Decoder)) {
+	dec := hpack.NewDecoder(bytes.NewReader(data))
+	if setup != nil {
+		setup(dec)
+	}
+	_ = dec.Decode(v)
+}
+
+func FuzzUnmarshalStruct(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = hpack.Unmarshal(data, new(fuzzPacket))
+		_ = hpack.Unmarshal(data, new(fuzzItem))
+		_ = hpack.Unmarshal(data, new(*fuzzArray))
+		_ = hpack.Unmarshal(data, new(fuzzDupHash))
+		_ = hpack.Unmarshal(data, new(fuzzBadTag))
+		_ = hpack.UnmarshalStrict(data, new(fuzzPacket))
+
+		// 값이 채워진 대상에 다시 디코딩
+		p := &fuzzPacket{Items: make([]fuzzItem, 1), ByName: map[string]*fuzzItem{}, Payload: &fuzzChat{}}
+		_ = hpack.Unmarshal(data, p)
+
+		fuzzDecode(data, new(fuzzPacket), func(d *hpack.Decoder) {
+			d.UseMsgpackCompat(true)
+			d.UseInternedStrings(true)
+			d.DisallowUnknownFields(true)
+		})
+		fuzzDecode(data, new(fuzzPacket), func(d *hpack.Decoder) {
+			d.SetLimits(hpack.DecodeLimits{MaxDepth: 8, MaxBytes: 1 << 12, MaxAlloc: 1 << 16})
+		})
+	})
+}
+
+func FuzzUnmarshalMap(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = hpack.Unmarshal(data, new(map[string]interface{}))
+		_ = hpack.Unmarshal(data, new(map[string]string))
+		_ = hpack.Unmarshal(data, new(map[int]*fuzzItem))
+		_ = hpack.Unmarshal(data, new(map[string][]byte))
+		_ = hpack.Unmarshal(data, new([]map[string]int))
+		_ = hpack.Unmarshal(data, new(map[float64][2]bool))
+		_ = hpack.Unmarshal(data, new(map[string]fuzzPayload))
+		_ = hpack.UnmarshalStrict(data, new(map[interface{}]int))
+	})
+}
+
+func FuzzDecodeInterface(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		_ = hpack.Unmarshal(data, &v)
+		_ = hpack.Unmarshal(data, new(fuzzPayload))
+		_ = hpack.Unmarshal(data, new(time.Time))
+		_ = hpack.Unmarshal(data, new([]interface{}))
+		_ = hpack.UnmarshalStrict(data, &v)
+
+		// interface에 담긴 포인터에 디코딩
+		var ptr interface{} = &fuzzItem{Next: &fuzzItem{}}
+		_ = hpack.Unmarshal(data, &ptr)
+
+		dec := hpack.NewDecoder(bytes.NewReader(data))
+		dec.UseInternedStrings(true)
+		_, _ = dec.DecodeInterface()
+
+		dec = hpack.NewDecoder(bytes.NewReader(data))
+		_, _ = dec.DecodeRaw()
+		_ = dec.Skip()
+
+		dec = hpack.NewDecoder(bytes.NewReader(data))
+		_, _ = dec.DecodeUntypedMap()
+		dec = hpack.NewDecoder(bytes.NewReader(data))
+		_, _ = dec.DecodeTypedMap()
+	})
+}
@@ -0,0 +1,82 @@
+package hpack
+
+import (
+	"hash/crc32"
+	"hash/fnv"
+	"reflect"
+	"sync"
+)
+
+// FieldHasher : 필드명을 32비트 해시로 변환
+//
+// 1B, 2B 해시는 32비트 해시를 XOR로 접어서(fold32to8, fold32to16) 만든다.
+// 구조체 캐시의 키로 사용되므로 비교 가능한(comparable) 타입이어야 한다.
+type FieldHasher interface {
+	Name() string // 스키마 기술자에 기록되는 이름
+	Hash32(name string) uint32
+}
+
+// 기본 제공 FieldHasher
+var (
+	CRC32IEEEHasher       FieldHasher = crc32Hasher{name: "crc32-ieee", table: crc32Table}
+	CRC32CastagnoliHasher FieldHasher = crc32Hasher{name: "crc32-castagnoli", table: crc32.MakeTable(crc32.Castagnoli)}
+	FNV1aHasher           FieldHasher = fnv1aHasher{}
+)
+
+var defaultFieldHasher = CRC32IEEEHasher
+
+// FieldHasherByName : Name()으로 기본 제공 FieldHasher 검색. 없으면 nil
+func FieldHasherByName(name string) FieldHasher {
+	for _, h := range []FieldHasher{CRC32IEEEHasher, CRC32CastagnoliHasher, FNV1aHasher} {
+		if h.Name() == name {
+			return h
+		}
+	}
+	return nil
+}
+
+var typeHasherMap sync.Map
+
+// RegisterFieldHasher : value 타입의 필드 해시 함수를 지정
+// 타입별 지정은 Encoder/Decoder의 SetFieldHasher보다 우선한다.
+func RegisterFieldHasher(value interface{}, hasher FieldHasher) {
+	typ := reflect.TypeOf(value)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if hasher == nil {
+		typeHasherMap.Delete(typ)
+		return
+	}
+	typeHasherMap.Store(typ, hasher)
+}
+
+// fieldHasherFor : typ에 적용할 FieldHasher (타입별 지정 > 코덱 설정 > 기본값)
+func fieldHasherFor(typ reflect.Type, hasher FieldHasher) FieldHasher {
+	if h, ok := typeHasherMap.Load(typ); ok {
+		return h.(FieldHasher)
+	}
+	if hasher == nil {
+		return defaultFieldHasher
+	}
+	return hasher
+}
+
+type crc32Hasher struct {
+	name  string
+	table *crc32.Table
+}
+
+func (h crc32Hasher) Name() string { return h.name }
+func (h crc32Hasher) Hash32(name string) uint32 {
+	return crc32.Checksum(StringToBytes(name), h.table)
+}
+
+type fnv1aHasher struct{}
+
+func (fnv1aHasher) Name() string { return "fnv1a-32" }
+func (fnv1aHasher) Hash32(name string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write(StringToBytes(name))
+	return h.Sum32()
+}
@@ -0,0 +1,92 @@
+package hpack_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type hPacket struct {
+	F2  int `msgpack:"f2"`
+	F50 int `msgpack:"f50"`
+}
+
+type hRegistered struct {
+	F2  int `msgpack:"f2"`
+	F50 int `msgpack:"f50"`
+}
+
+func init() {
+	hpack.RegisterFieldHasher(hRegistered{}, hpack.FNV1aHasher)
+}
+
+func TestFieldHashers(t *testing.T) {
+	tests := []struct {
+		hasher hpack.FieldHasher
+		size   hpack.FieldNameSizeFlag
+	}{
+		{hpack.CRC32IEEEHasher, hpack.FieldNameSizeFlag2Byte}, // f2, f50의 1B 해시가 같음
+		{hpack.CRC32CastagnoliHasher, hpack.FieldNameSizeFlag1Byte},
+		{hpack.FNV1aHasher, hpack.FieldNameSizeFlag1Byte},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hasher.Name(), func(t *testing.T) {
+			if h := hpack.FieldHasherByName(tt.hasher.Name()); h != tt.hasher {
+				t.Fatalf("FieldHasherByName = %v", h)
+			}
+
+			s, err := hpack.SchemaOfHasher(reflect.TypeOf(hPacket{}), tt.hasher)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Types[0].Hasher != tt.hasher.Name() || s.Types[0].Field("f2").Size != tt.size {
+				t.Fatalf("schema = %+v", s.Types[0])
+			}
+
+			err = hpack.ValidateWith(reflect.TypeOf(hPacket{}), hpack.SchemaOptions{Hasher: tt.hasher})
+			if widened := err != nil; widened != (tt.size != hpack.FieldNameSizeFlag1Byte) {
+				t.Fatalf("ValidateWith = %v", err)
+			}
+
+			var buf bytes.Buffer
+			enc := hpack.NewEncoder(&buf)
+			enc.SetFieldHasher(tt.hasher)
+			if err := enc.Encode(&hPacket{F2: 1, F50: 2}); err != nil {
+				t.Fatal(err)
+			}
+			var out hPacket
+			dec := hpack.NewDecoder(bytes.NewReader(buf.Bytes()))
+			dec.SetFieldHasher(tt.hasher)
+			if err := dec.Decode(&out); err != nil || out != (hPacket{F2: 1, F50: 2}) {
+				t.Fatalf("got %+v, err %v", out, err)
+			}
+		})
+	}
+}
+
+func TestRegisterFieldHasher(t *testing.T) {
+	// 타입별 지정은 코덱 설정보다 우선한다.
+	s, err := hpack.SchemaOfHasher(reflect.TypeOf(hRegistered{}), hpack.CRC32CastagnoliHasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Types[0].Hasher != hpack.FNV1aHasher.Name() {
+		t.Fatalf("hasher = %s, want %s", s.Types[0].Hasher, hpack.FNV1aHasher.Name())
+	}
+	if err := hpack.Validate(reflect.TypeOf(hRegistered{})); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := hpack.Marshal(&hRegistered{F2: 1, F50: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out hRegistered
+	dec := hpack.NewDecoder(bytes.NewReader(b))
+	dec.SetFieldHasher(hpack.CRC32IEEEHasher)
+	if err := dec.Decode(&out); err != nil || out != (hRegistered{F2: 1, F50: 2}) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+}
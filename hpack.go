@@ -17,6 +17,14 @@ const (
 	FieldNameSizeFlag1Byte FieldNameSizeFlag = 0x00 // (00000000)
 	FieldNameSizeFlag2Byte FieldNameSizeFlag = 0x40 // (01000000)
 	FieldNameSizeFlag4Byte FieldNameSizeFlag = 0x80 // (10000000)
+
+	// FieldNameSizeFlagMixed : 필드마다 해시 크기가 다름
+	// 플래그 뒤에 필드별 크기 비트맵(필드당 2bit, 상위 비트부터, ceil(N/4) 바이트)이 온다.
+	// 각 2bit 값은 해당 필드 크기 플래그의 상위 2bit(0: 1B, 1: 2B, 2: 4B)
+	FieldNameSizeFlagMixed FieldNameSizeFlag = 0xC0 // (11000000)
+
+	// FieldNameSizeFlagString : 필드명을 해시 대신 msgpack 문자열로 기록 (Encoder.UseFieldNames)
+	FieldNameSizeFlagString FieldNameSizeFlag = 0x20 // (00100000)
 )
 
 func (f FieldNameSizeFlag) ToString() string {
@@ -27,9 +35,12 @@ func (f FieldNameSizeFlag) ToString() string {
 		return "2Byte"
 	case FieldNameSizeFlag4Byte:
 		return "4Byte"
+	case FieldNameSizeFlagMixed:
+		return "Mixed"
+	case FieldNameSizeFlagString:
+		return "String"
 	}
 
-	fmt.Errorf("unknown FieldNameSizeFlag: %v", f)
 	return "Unknown"
 }
 func (f FieldNameSizeFlag) ToSize() int {
@@ -42,10 +53,14 @@ func (f FieldNameSizeFlag) ToSize() int {
 		return 4
 	}
 
-	fmt.Errorf("unknown FieldNameSizeFlag: %v", f)
 	return 0
 }
 
+// mixedWidthsLen : 필드 n개의 크기 비트맵 바이트 수
+func mixedWidthsLen(n int) int {
+	return (n + 3) / 4
+}
+
 var fieldNameSizeFlagValues = []FieldNameSizeFlag{
 	FieldNameSizeFlag1Byte,
 	FieldNameSizeFlag2Byte,
@@ -54,6 +69,26 @@ var fieldNameSizeFlagValues = []FieldNameSizeFlag{
 
 const defaultStructTag = "msgpack"
 
+// structTags : 필드 태그를 읽을 태그 키
+// primary가 비어 있으면 defaultStructTag를 읽고, primary 태그가 없으면 fallback 태그를 읽는다.
+type structTags struct {
+	primary  string
+	fallback string
+}
+
+func (t structTags) get(f reflect.StructField) string {
+	primary := t.primary
+	if primary == "" {
+		primary = defaultStructTag
+	}
+
+	tagStr := f.Tag.Get(primary)
+	if tagStr == "" && t.fallback != "" {
+		tagStr = f.Tag.Get(t.fallback)
+	}
+	return tagStr
+}
+
 var structs = newStructCache()
 
 type structCache struct {
@@ -61,32 +96,60 @@ type structCache struct {
 }
 
 type structCacheKey struct {
-	typ reflect.Type
+	typ    reflect.Type
+	hasher FieldHasher
+	tags   structTags
 }
 
 func newStructCache() *structCache {
 	return new(structCache)
 }
 
-func (m *structCache) Fields(typ reflect.Type) *fields {
-	key := structCacheKey{typ: typ}
+// Fields : 인코딩/디코딩에 사용할 typ의 필드 목록. hasher가 nil이면 기본 FieldHasher(CRC32-IEEE)를 사용
+// RegisterFieldHasher로 타입별 해시 함수를 지정했으면 hasher보다 우선한다.
+// 필드명과 옵션은 tags의 태그 키로 읽으므로 (타입, 해시 함수, 태그)별로 캐시한다.
+// 태그 오류(IssueInvalid)가 있으면 에러를 반환
+func (m *structCache) Fields(typ reflect.Type, hasher FieldHasher, tags structTags) (*fields, error) {
+	fs := m.load(typ, hasher, tags)
+	if fs.err != nil {
+		return nil, fs.err
+	}
+	return fs, nil
+}
+
+// load : Fields와 같지만 태그 오류가 있어도 필드 목록을 반환 (Validate, SchemaOf)
+func (m *structCache) load(typ reflect.Type, hasher FieldHasher, tags structTags) *fields {
+	key := structCacheKey{typ: typ, hasher: fieldHasherFor(typ, hasher), tags: tags}
 
 	if v, ok := m.m.Load(key); ok {
 		return v.(*fields)
 	}
 
-	fs := getFields(typ)
+	fs := getFields(typ, key.hasher, tags)
 	m.m.Store(key, fs)
 
 	return fs
 }
 
 type fields struct {
-	Type reflect.Type
-	Map  map[uint32]*Field
-	List []*Field
+	Type   reflect.Type
+	Map    map[uint32]*Field
+	List   []*Field
+	Hasher FieldHasher
 
+	AsArray      bool // `_hpack struct{} hpack:",as_array"` 필드명 없이 배열로 인코딩
 	hasOmitEmpty bool
+	unknown      *Field        // `,unknown` 태그를 붙인 UnknownFields 필드. 없으면 nil
+	issues       []SchemaIssue // getFields에서 발견된 문제(Validate 참고)
+	err          error         // 첫 번째 IssueInvalid의 에러
+}
+
+// invalid : 태그 오류로 제외한 필드를 IssueInvalid로 보고
+func (fs *fields) invalid(field string, err error) {
+	fs.issues = append(fs.issues, SchemaIssue{Kind: IssueInvalid, Type: fs.Type, Field: field, Err: err})
+	if fs.err == nil {
+		fs.err = err
+	}
 }
 
 // IEEE: 가장 흔한 CRC-32 (0x04C11DB7)
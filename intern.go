@@ -55,6 +55,52 @@ func encodeInternedStringValue(e *Encoder, v reflect.Value) error {
 	return e.encodeInternedString(v.String(), true)
 }
 
+// encodeInternedStringSliceValue : `intern` 태그가 붙은 []string
+func encodeInternedStringSliceValue(e *Encoder, v reflect.Value) error {
+	if v.IsNil() {
+		return e.EncodeNil()
+	}
+	if err := e.encodeArrayLen(v.Len()); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encodeInternedString(v.Index(i).String(), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeInternedMapValue : `intern` 태그가 붙은 map[string]T. 키만 intern 한다.
+func encodeInternedMapValue(e *Encoder, v reflect.Value) error {
+	if v.IsNil() {
+		return e.EncodeNil()
+	}
+	if err := e.encodeMapLen(v.Len()); err != nil {
+		return err
+	}
+
+	var keys []reflect.Value
+	if e.flags&sortMapKeysFlag != 0 {
+		var err error
+		if keys, err = sortedMapKeys(v); err != nil {
+			return err
+		}
+	} else {
+		keys = v.MapKeys()
+	}
+
+	for _, k := range keys {
+		if err := e.encodeInternedString(k.String(), true); err != nil {
+			return err
+		}
+		if err := e.EncodeValue(v.MapIndex(k)); err != nil {
+			return e.elemError(err, keySegment(k))
+		}
+	}
+	return nil
+}
+
 func (e *Encoder) encodeInternedString(s string, intern bool) error {
 	// Interned string takes at least 3 bytes. Plain string 1 byte + string len.
 	if idx, ok := e.dict[s]; ok {
@@ -100,21 +146,21 @@ func (e *Encoder) encodeInternedStringIndex(idx int) error {
 //------------------------------------------------------------------------------
 
 func decodeInternedInterfaceValue(d *Decoder, v reflect.Value) error {
-	s, err := d.decodeInternedString(true)
-	if err == nil {
-		v.Set(reflect.ValueOf(s))
-		return nil
-	}
+	// 문자열이 아니면(interned string ext 포함) 일반 interface로 디코딩
+	c, err := d.PeekCode()
 	if err != nil {
-		if _, ok := err.(unexpectedCodeError); !ok {
-			return err
-		}
+		return err
+	}
+	if !msgpcode.IsString(c) {
+		return decodeInterfaceValue(d, v)
 	}
 
-	if err := d.s.UnreadByte(); err != nil {
+	s, err := d.decodeInternedString(true)
+	if err != nil {
 		return err
 	}
-	return decodeInterfaceValue(d, v)
+	v.Set(reflect.ValueOf(s))
+	return nil
 }
 
 func decodeInternedStringValue(d *Decoder, v reflect.Value) error {
@@ -127,20 +173,90 @@ func decodeInternedStringValue(d *Decoder, v reflect.Value) error {
 	return nil
 }
 
+func decodeInternedStringSliceValue(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	if n == -1 {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if err := d.allocLen(n, v.Type().Elem().Size()); err != nil {
+		return err
+	}
+
+	ln := d.allocCap(n, sliceAllocLimit)
+	ss := reflect.MakeSlice(v.Type(), 0, ln)
+	elem := reflect.New(v.Type().Elem()).Elem()
+	for i := 0; i < n; i++ {
+		s, err := d.decodeInternedString(true)
+		if err != nil {
+			return err
+		}
+		elem.SetString(s)
+		ss = reflect.Append(ss, elem)
+	}
+	v.Set(ss)
+
+	return nil
+}
+
+func decodeInternedMapValue(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+
+	typ := v.Type()
+	if n == -1 {
+		v.Set(reflect.Zero(typ))
+		return nil
+	}
+	if err := d.allocLen(n, mapEntrySize(typ)); err != nil {
+		return err
+	}
+	if err := d.enterDepth(); err != nil {
+		return err
+	}
+	defer d.leaveDepth()
+
+	if v.IsNil() {
+		ln := d.allocCap(n, maxMapSize)
+		v.Set(reflect.MakeMapWithSize(typ, ln))
+	}
+
+	seen := newKeySet[string](d)
+	for i := 0; i < n; i++ {
+		s, err := d.decodeInternedString(true)
+		if err != nil {
+			return err
+		}
+		if !seen.add(s) {
+			return errDuplicateMapKey(s)
+		}
+		mk := reflect.New(typ.Key()).Elem()
+		mk.SetString(s)
+
+		mv := d.newValue(typ.Elem()).Elem()
+		if err := d.DecodeValue(mv); err != nil {
+			return d.elemError(err, keySegment(mk))
+		}
+
+		v.SetMapIndex(mk, mv)
+	}
+
+	return nil
+}
+
 func (d *Decoder) decodeInternedString(intern bool) (string, error) {
 	c, err := d.readCode()
 	if err != nil {
 		return "", err
 	}
 
-	if msgpcode.IsFixedString(c) {
-		n := int(c & msgpcode.FixedStrMask)
-		return d.decodeInternedStringWithLen(n, intern)
-	}
-
 	switch c {
-	case msgpcode.Nil:
-		return "", nil
 	case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4:
 		typeID, extLen, err := d.extHeader(c)
 		if err != nil {
@@ -158,30 +274,24 @@ func (d *Decoder) decodeInternedString(intern bool) (string, error) {
 		}
 
 		return d.internedStringAtIndex(idx)
-	case msgpcode.Str8, msgpcode.Bin8:
-		n, err := d.uint8()
-		if err != nil {
-			return "", err
-		}
-		return d.decodeInternedStringWithLen(int(n), intern)
-	case msgpcode.Str16, msgpcode.Bin16:
-		n, err := d.uint16()
-		if err != nil {
-			return "", err
-		}
-		return d.decodeInternedStringWithLen(int(n), intern)
-	case msgpcode.Str32, msgpcode.Bin32:
-		n, err := d.uint32()
-		if err != nil {
-			return "", err
+	}
+
+	if c != msgpcode.Nil && !msgpcode.IsString(c) && !msgpcode.IsBin(c) {
+		return "", unexpectedCodeError{
+			code: c,
+			hint: "interned string",
 		}
-		return d.decodeInternedStringWithLen(int(n), intern)
 	}
 
-	return "", unexpectedCodeError{
-		code: c,
-		hint: "interned string",
+	// 길이 제한(MaxStringLen, MaxBinLen, MaxAlloc)은 일반 문자열과 같이 bytesLen에서 검사
+	n, err := d.bytesLen(c)
+	if err != nil {
+		return "", err
+	}
+	if n == -1 {
+		return "", nil
 	}
+	return d.decodeInternedStringWithLen(n, intern)
 }
 
 func (d *Decoder) decodeInternedStringIndex(extLen int) (int, error) {
@@ -197,12 +307,18 @@ func (d *Decoder) decodeInternedStringIndex(extLen int) (int, error) {
 		if err != nil {
 			return 0, err
 		}
+		if d.strict() && n <= math.MaxUint8 {
+			return 0, strictErrorf("interned string index %d encoded in %d bytes", n, extLen)
+		}
 		return int(n), nil
 	case 4:
 		n, err := d.uint32()
 		if err != nil {
 			return 0, err
 		}
+		if d.strict() && n <= math.MaxUint16 {
+			return 0, strictErrorf("interned string index %d encoded in %d bytes", n, extLen)
+		}
 		return int(n), nil
 	}
 
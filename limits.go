@@ -0,0 +1,106 @@
+package hpack
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrLimitExceeded : DecodeLimits의 제한을 넘었을 때. errors.Is(err, ErrLimitExceeded)로 확인
+var ErrLimitExceeded = errors.New("hpack: decode limit exceeded")
+
+// DecodeLimits : 신뢰할 수 없는 입력을 디코딩할 때의 제한. 0이면 제한하지 않음
+//
+// MaxBytes, MaxAlloc은 SetLimits(또는 Reset) 이후 누적된 값에 적용된다.
+// 스트림에서 여러 메시지를 디코딩하는 경우 메시지마다 SetLimits를 다시 호출한다.
+type DecodeLimits struct {
+	MaxDepth     int   // array, map, struct의 최대 중첩 깊이
+	MaxBytes     int64 // 읽을 수 있는 최대 바이트 수
+	MaxStringLen int   // 문자열 하나의 최대 길이(바이트)
+	MaxBinLen    int   // 바이너리, ext 하나의 최대 길이(바이트)
+	MaxArrayLen  int   // array 하나의 최대 원소 수
+	MaxMapLen    int   // map, struct 하나의 최대 원소(필드) 수
+	MaxAlloc     int64 // 길이 헤더로 할당하는 메모리의 합(문자열/바이너리 길이, 슬라이스·map 원소 크기 × 개수)
+}
+
+// LimitError : DecodeLimits의 제한을 넘었을 때 반환되는 에러
+type LimitError struct {
+	Limit string // 넘은 제한. DecodeLimits의 필드명 (예: "MaxArrayLen")
+	Max   int64  // 설정된 제한 값
+	Value int64  // 입력이 요구한 값
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("hpack: %s exceeded (%d > %d)", e.Limit, e.Value, e.Max)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// SetLimits : 이후 디코딩에 적용할 제한을 설정하고, MaxBytes, MaxAlloc의 누적값을 초기화
+func (d *Decoder) SetLimits(limits DecodeLimits) {
+	d.limits = limits
+	d.limitBase = d.read
+	d.alloc = 0
+}
+
+// enterDepth : array, map, struct를 디코딩하기 전에 호출. 성공하면 leaveDepth를 호출해야 한다.
+func (d *Decoder) enterDepth() error {
+	if d.limits.MaxDepth > 0 && d.depth >= d.limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Max: int64(d.limits.MaxDepth), Value: int64(d.depth + 1)}
+	}
+	d.depth++
+	return nil
+}
+
+func (d *Decoder) leaveDepth() {
+	d.depth--
+}
+
+// checkRead : n 바이트를 더 읽기 전에 MaxBytes를 넘지 않는지 확인
+func (d *Decoder) checkRead(n int) error {
+	if d.limits.MaxBytes > 0 && d.read+int64(n)-d.limitBase > d.limits.MaxBytes {
+		return &LimitError{Limit: "MaxBytes", Max: d.limits.MaxBytes, Value: d.read + int64(n) - d.limitBase}
+	}
+	return nil
+}
+
+// checkLen : 길이 헤더 n을 max와 비교하고, n*size 바이트를 할당량에 더함
+func (d *Decoder) checkLen(limit string, max, n int, size uintptr) error {
+	if n <= 0 {
+		return nil
+	}
+	if max > 0 && n > max {
+		return &LimitError{Limit: limit, Max: int64(max), Value: int64(n)}
+	}
+	if d.limits.MaxAlloc > 0 {
+		d.alloc += int64(n) * int64(size)
+		if d.alloc > d.limits.MaxAlloc {
+			return &LimitError{Limit: "MaxAlloc", Max: d.limits.MaxAlloc, Value: d.alloc}
+		}
+	}
+	return nil
+}
+
+// allocLen : 길이 제한 없이 크기가 size인 원소 n개를 할당량에 더함
+func (d *Decoder) allocLen(n int, size uintptr) error {
+	return d.checkLen("", 0, n, size)
+}
+
+func (d *Decoder) checkArrayLen(n int) error {
+	return d.checkLen("MaxArrayLen", d.limits.MaxArrayLen, n, 0)
+}
+
+func (d *Decoder) checkMapLen(n int) error {
+	return d.checkLen("MaxMapLen", d.limits.MaxMapLen, n, 0)
+}
+
+func mapEntrySize(typ reflect.Type) uintptr {
+	return typ.Key().Size() + typ.Elem().Size()
+}
+
+// checkExtLen : ext payload는 바이너리와 같이 MaxBinLen을 적용
+func (d *Decoder) checkExtLen(n int) error {
+	return d.checkLen("MaxBinLen", d.limits.MaxBinLen, n, 1)
+}
@@ -0,0 +1,127 @@
+package hpack_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	marshal := func(v interface{}) []byte {
+		b, err := hpack.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	tests := []struct {
+		limit  string
+		limits hpack.DecodeLimits
+		b      []byte
+		v      func() interface{}
+	}{
+		{"MaxDepth", hpack.DecodeLimits{MaxDepth: 2}, marshal([]interface{}{[]interface{}{[]int{1}}}), func() interface{} { return new(interface{}) }},
+		{"MaxDepth", hpack.DecodeLimits{MaxDepth: 1}, marshal(&withStruct{}), func() interface{} { return new(withStruct) }},
+		{"MaxBytes", hpack.DecodeLimits{MaxBytes: 4}, marshal("hello world"), func() interface{} { return new(string) }},
+		{"MaxStringLen", hpack.DecodeLimits{MaxStringLen: 3}, marshal("hello"), func() interface{} { return new(string) }},
+		{"MaxBinLen", hpack.DecodeLimits{MaxBinLen: 2}, marshal([]byte{1, 2, 3}), func() interface{} { return new([]byte) }},
+		{"MaxArrayLen", hpack.DecodeLimits{MaxArrayLen: 2}, marshal([]int{1, 2, 3}), func() interface{} { return new([]int) }},
+		{"MaxMapLen", hpack.DecodeLimits{MaxMapLen: 1}, marshal(map[string]int{"a": 1, "b": 2}), func() interface{} { return new(map[string]int) }},
+		{"MaxMapLen", hpack.DecodeLimits{MaxMapLen: 1}, marshal(&vClean{ID: 1}), func() interface{} { return new(vClean) }},
+		{"MaxAlloc", hpack.DecodeLimits{MaxAlloc: 16}, marshal([]int64{1, 2, 3}), func() interface{} { return new([]int64) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			if err := decode(tt.b, tt.v(), nil); err != nil {
+				t.Fatalf("without limits: %v", err)
+			}
+
+			err := decode(tt.b, tt.v(), func(dec *hpack.Decoder) { dec.SetLimits(tt.limits) })
+			var lerr *hpack.LimitError
+			if !errors.As(err, &lerr) || lerr.Limit != tt.limit || !errors.Is(err, hpack.ErrLimitExceeded) {
+				t.Fatalf("got %v, want %s exceeded", err, tt.limit)
+			}
+		})
+	}
+
+	// 길이 헤더만 큰 입력은 할당하기 전에 거부한다.
+	hostile := []byte{hpack.Array32, 0x40, 0, 0, 0}
+	err := decode(hostile, new([]int), func(dec *hpack.Decoder) { dec.SetLimits(hpack.DecodeLimits{MaxArrayLen: 1 << 10}) })
+	if !errors.Is(err, hpack.ErrLimitExceeded) {
+		t.Fatalf("hostile array header: got %v", err)
+	}
+}
+
+type limitIntern struct {
+	Name string `msgpack:"name,intern"`
+}
+
+func TestDecodeLimitsInterned(t *testing.T) {
+	interned := func(dec *hpack.Decoder) { dec.UseInternedStrings(true) }
+	tests := []struct {
+		name   string
+		limits hpack.DecodeLimits
+		in     interface{}
+		v      func() interface{}
+		setup  func(*hpack.Decoder)
+	}{
+		{"intern field", hpack.DecodeLimits{MaxStringLen: 3}, &limitIntern{Name: "hello"}, func() interface{} { return new(limitIntern) }, nil},
+		{"UseInternedStrings", hpack.DecodeLimits{MaxStringLen: 3}, "hello", func() interface{} { return new(string) }, interned},
+		{"bin as string", hpack.DecodeLimits{MaxBinLen: 2}, []byte("hello"), func() interface{} { return new(string) }, interned},
+		{"alloc", hpack.DecodeLimits{MaxAlloc: 4}, &limitIntern{Name: "hello"}, func() interface{} { return new(limitIntern) }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hpack.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decode(b, tt.v(), tt.setup); err != nil {
+				t.Fatalf("without limits: %v", err)
+			}
+
+			err = decode(b, tt.v(), func(dec *hpack.Decoder) {
+				if tt.setup != nil {
+					tt.setup(dec)
+				}
+				dec.SetLimits(tt.limits)
+			})
+			if !errors.Is(err, hpack.ErrLimitExceeded) {
+				t.Fatalf("got %v, want ErrLimitExceeded", err)
+			}
+		})
+	}
+}
+
+func TestDecodeLimitsPerMessage(t *testing.T) {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	for i := 0; i < 3; i++ {
+		if err := enc.Encode("hello"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// MaxBytes는 SetLimits 이후 누적되므로 메시지마다 다시 설정한다.
+	limits := hpack.DecodeLimits{MaxBytes: 8}
+	dec := hpack.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for i := 0; i < 3; i++ {
+		dec.SetLimits(limits)
+		var s string
+		if err := dec.Decode(&s); err != nil || s != "hello" {
+			t.Fatalf("message %d: got %q, err %v", i, s, err)
+		}
+	}
+
+	dec = hpack.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.SetLimits(limits)
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&s); !errors.Is(err, hpack.ErrLimitExceeded) {
+		t.Fatalf("second message without SetLimits: got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package hpack
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Logger : hpack 내부 로그(알 수 없는 필드, 해시 충돌 등)를 받는 인터페이스. *log.Logger가 구현한다.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// UnknownFieldFunc : 디코딩 대상 struct에 없는 필드를 만났을 때 호출되는 함수
+//
+// hash, width는 입력에 담긴 필드 해시와 해시 크기. 문자열 키(FieldNameSizeFlagString)이면 hash는 필드명의 4B 해시
+// raw는 필드 값의 인코딩된 바이트. 호출이 끝나면 재사용될 수 있으므로 보관하려면 복사해야 한다.
+type UnknownFieldFunc func(structType reflect.Type, hash uint32, width FieldNameSizeFlag, raw RawMessage)
+
+var globalLogger atomic.Pointer[Logger]
+
+// SetLogger : 전역 Logger 설정. nil이면 출력하지 않는다. (기본값)
+//
+// struct 필드 정보는 타입별로 한 번 만들어 공유하므로, 그 과정의 로그(해시 충돌 등)는 항상 전역 Logger로 출력된다.
+func SetLogger(l Logger) {
+	if l == nil {
+		globalLogger.Store(nil)
+		return
+	}
+	globalLogger.Store(&l)
+}
+
+func logf(format string, v ...interface{}) {
+	if l := globalLogger.Load(); l != nil {
+		(*l).Printf(format, v...)
+	}
+}
+
+// SetLogger : 이 Decoder의 로그를 l로 출력. nil이면 전역 Logger(SetLogger)를 사용한다.
+func (d *Decoder) SetLogger(l Logger) {
+	d.logger = l
+}
+
+// logging : 출력할 Logger가 있는지 여부. 자주 호출되는 경로에서 인자를 만들기 전에 확인한다.
+func (d *Decoder) logging() bool {
+	return d.logger != nil || globalLogger.Load() != nil
+}
+
+func (d *Decoder) logf(format string, v ...interface{}) {
+	if d.logger != nil {
+		d.logger.Printf(format, v...)
+		return
+	}
+	logf(format, v...)
+}
+
+// OnUnknownField : 디코딩 대상 struct에 없는 필드를 만나면 fn을 호출. nil이면 호출하지 않는다.
+// DisallowUnknownFields가 켜져 있으면 fn을 호출하지 않고 에러를 반환한다.
+func (d *Decoder) OnUnknownField(fn UnknownFieldFunc) {
+	d.onUnknownField = fn
+}
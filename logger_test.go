@@ -0,0 +1,107 @@
+package hpack_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type logRecorder struct{ lines []string }
+
+func (r *logRecorder) Printf(format string, v ...interface{}) {
+	r.lines = append(r.lines, fmt.Sprintf(format, v...))
+}
+
+func TestDecoderLogger(t *testing.T) {
+	b, err := hpack.Marshal(&withMap{M: map[int]string{1: "a"}, Tail: "zz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rec logRecorder
+	var out tailOnly
+	if err := decode(b, &out, func(dec *hpack.Decoder) { dec.SetLogger(&rec) }); err != nil || out.Tail != "zz" {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+	if len(rec.lines) != 1 || !strings.Contains(rec.lines[0], "unknown field") || !strings.Contains(rec.lines[0], "tailOnly") {
+		t.Fatalf("logs = %q", rec.lines)
+	}
+
+	// 모르는 필드가 없으면 출력하지 않는다.
+	rec.lines = nil
+	if err := decode(b, &withMap{}, func(dec *hpack.Decoder) { dec.SetLogger(&rec) }); err != nil || len(rec.lines) != 0 {
+		t.Fatalf("logs = %q, err %v", rec.lines, err)
+	}
+}
+
+func TestGlobalLogger(t *testing.T) {
+	var rec logRecorder
+	hpack.SetLogger(&rec)
+	defer hpack.SetLogger(nil)
+
+	// 필드 정보 로그(별칭 충돌)는 전역 Logger로 출력된다.
+	type aliasConflict struct {
+		A int `msgpack:"a"`
+		B int `msgpack:"b,alias=a"`
+	}
+	if _, err := hpack.Marshal(&aliasConflict{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.lines) == 0 {
+		t.Fatal("no field log")
+	}
+
+	// Decoder Logger가 없으면 전역 Logger를 사용한다.
+	rec.lines = nil
+	b, err := hpack.Marshal(&withMap{Tail: "zz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hpack.Unmarshal(b, &tailOnly{}); err != nil || len(rec.lines) != 1 {
+		t.Fatalf("logs = %q, err %v", rec.lines, err)
+	}
+
+	hpack.SetLogger(nil)
+	rec.lines = nil
+	if err := hpack.Unmarshal(b, &tailOnly{}); err != nil || len(rec.lines) != 0 {
+		t.Fatalf("logs after SetLogger(nil) = %q, err %v", rec.lines, err)
+	}
+}
+
+func TestOnUnknownField(t *testing.T) {
+	in := withMap{M: map[int]string{1: "a"}, Tail: "zz"}
+	b, err := hpack.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := schemaOf(t, withMap{}).Types[0].Field("m").Hash
+
+	var calls int
+	onUnknown := func(typ reflect.Type, h uint32, width hpack.FieldNameSizeFlag, raw hpack.RawMessage) {
+		calls++
+		var m map[int]string
+		if err := hpack.Unmarshal(raw, &m); err != nil || !reflect.DeepEqual(m, in.M) {
+			t.Errorf("raw = % x, err %v", raw, err)
+		}
+		if typ != reflect.TypeOf(tailOnly{}) || h != hash || width != hpack.FieldNameSizeFlag1Byte {
+			t.Errorf("got %v %#x %s", typ, h, width.ToString())
+		}
+	}
+	var out tailOnly
+	if err := decode(b, &out, func(dec *hpack.Decoder) { dec.OnUnknownField(onUnknown) }); err != nil || out.Tail != "zz" || calls != 1 {
+		t.Fatalf("got %+v, calls %d, err %v", out, calls, err)
+	}
+
+	// DisallowUnknownFields이면 호출하지 않고 에러를 반환한다.
+	calls = 0
+	err = decode(b, &out, func(dec *hpack.Decoder) {
+		dec.OnUnknownField(onUnknown)
+		dec.DisallowUnknownFields(true)
+	})
+	if err == nil || calls != 0 {
+		t.Fatalf("calls %d, err %v", calls, err)
+	}
+}
@@ -0,0 +1,181 @@
+package hpack
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// typeIDExtID : 등록된 타입의 ID를 함께 담는 ext (음수 타입은 msgpack 예약이므로 애플리케이션 범위의 마지막 값)
+// payload = 타입 ID(uint) + hpack으로 인코딩된 값
+const typeIDExtID int8 = 127
+
+// structTypeID : 등록되지 않은 hpack struct가 interface에 담긴 경우에 사용하는 예약 타입 ID
+// 타입 정보 없이 읽는 쪽(DecodeInterface, Skip)이 map과 struct를 구분할 수 있도록 한다.
+const structTypeID uint32 = 0
+
+var (
+	registeredTypes   sync.Map // reflect.Type -> uint32
+	registeredTypeIDs sync.Map // uint32 -> reflect.Type
+)
+
+// RegisterType : interface 타입 필드(슬라이스 원소, map 값 포함)에 담긴 T 값을 타입 ID와 함께 인코딩하도록 등록
+//
+// 디코딩 시 타입 ID로 T 값을 다시 만들어 interface에 채운다. (일반 map으로 디코딩되지 않음)
+// 포인터 리시버로 interface를 구현하는 경우 *T를 등록해야 한다.
+// ID 0은 등록되지 않은 struct에 예약되어 있다. 0이나 같은 ID를 다른 타입에, 같은 타입을 다른 ID로 등록하면 panic
+//
+//	hpack.RegisterType[*LoginPacket](1)
+//	hpack.RegisterType[*ChatPacket](2)
+func RegisterType[T any](id uint32) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Interface {
+		panic(fmt.Errorf("hpack: RegisterType(%s): interface type", typ))
+	}
+	if id == structTypeID {
+		panic(fmt.Errorf("hpack: RegisterType(%s): id %d is reserved", typ, id))
+	}
+
+	if t, ok := registeredTypeIDs.Load(id); ok && t.(reflect.Type) != typ {
+		panic(fmt.Errorf("hpack: RegisterType(%s): id %d is already registered to %s", typ, id, t))
+	}
+	if v, ok := registeredTypes.Load(typ); ok && v.(uint32) != id {
+		panic(fmt.Errorf("hpack: RegisterType(%s): already registered with id %d", typ, v))
+	}
+
+	registeredTypes.Store(typ, id)
+	registeredTypeIDs.Store(id, typ)
+}
+
+func registeredTypeID(typ reflect.Type) (uint32, bool) {
+	v, ok := registeredTypes.Load(typ)
+	if !ok {
+		return 0, false
+	}
+	return v.(uint32), true
+}
+
+// encodeInterface : interface에 담긴 값 v를 인코딩
+func (e *Encoder) encodeInterface(v interface{}) error {
+	if v == nil {
+		return e.EncodeNil()
+	}
+	return e.encodeInterfaceElem(reflect.ValueOf(v))
+}
+
+// encodeInterfaceElem : interface에서 꺼낸 값 v를 인코딩
+// 등록된 타입이면 타입 ID와 함께 인코딩하고, 그 밖의 값에 들어 있는 struct는 structTypeID와 함께 ext로 감싼다.
+func (e *Encoder) encodeInterfaceElem(v reflect.Value) error {
+	flags := e.flags
+	defer func() { e.flags = flags }()
+
+	id, ok := registeredTypeID(v.Type())
+	if !ok {
+		e.flags |= structExtFlag
+		return e.EncodeValue(v)
+	}
+
+	// 등록된 타입은 타입을 알고 디코딩하므로 하위 struct를 감싸지 않는다.
+	e.flags &^= structExtFlag
+	return e.encodeExtValue(typeIDExtID, func() error {
+		if err := e.EncodeUint(uint64(id)); err != nil {
+			return err
+		}
+		return e.EncodeValue(v)
+	})
+}
+
+// encodeExtValue : ext 길이를 먼저 써야 하므로 fn이 쓰는 payload를 임시 버퍼에 인코딩한 뒤 복사
+func (e *Encoder) encodeExtValue(extID int8, fn func() error) error {
+	var buf bytes.Buffer
+
+	w := e.w
+	e.w = &buf
+	err := fn()
+	e.w = w
+	if err != nil {
+		return err
+	}
+
+	if err := e.EncodeExtHeader(extID, buf.Len()); err != nil {
+		return err
+	}
+	return e.write(buf.Bytes())
+}
+
+// decodeRegisteredValue : typeIDExtID ext의 payload를 등록된 타입의 값으로 디코딩
+// 등록되지 않은 struct(structTypeID)는 필드 해시를 키로 하는 map[uint32]interface{}로,
+// 필드명을 문자열로 기록한 struct(UseFieldNames)는 map[string]interface{}로 디코딩
+func (d *Decoder) decodeRegisteredValue(extLen int) (interface{}, error) {
+	if extLen == 0 {
+		return nil, fmt.Errorf("hpack: empty type id ext")
+	}
+
+	start := d.read
+	id, err := d.DecodeUint32()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeTypeIDValue(id, start, extLen)
+}
+
+// decodeTypeIDValue : typeIDExtID ext payload에서 타입 ID(id) 뒤의 값을 디코딩
+func (d *Decoder) decodeTypeIDValue(id uint32, start int64, extLen int) (interface{}, error) {
+	if id == structTypeID {
+		v, err := d.decodeStructExt()
+		if err != nil {
+			return nil, err
+		}
+		if err := d.checkExtRead(start, extLen); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	t, ok := registeredTypeIDs.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("hpack: unregistered type id %d", id)
+	}
+
+	v := d.newValue(t.(reflect.Type)).Elem()
+	if err := d.DecodeValue(v); err != nil {
+		return nil, err
+	}
+	if err := d.checkExtRead(start, extLen); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// decodeStructExt : 타입 ID 뒤의 struct(map 헤더 + 필드명 크기 플래그 + 필드)를 타입 정보 없이 디코딩
+func (d *Decoder) decodeStructExt() (interface{}, error) {
+	c, err := d.readCode()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.mapLen(c)
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 {
+		return nil, unexpectedCodeError{code: c, hint: "struct map length"}
+	}
+	fieldLen, err := d.decodeFieldLen()
+	if err != nil {
+		return nil, err
+	}
+
+	if fieldLen == FieldNameSizeFlagString {
+		return d.decodeMapN(n)
+	}
+	return d.decodeStructInterface(n, fieldLen)
+}
+
+// checkExtRead : start 이후 읽은 바이트 수가 ext payload 길이와 같은지 확인
+func (d *Decoder) checkExtRead(start int64, extLen int) error {
+	if n := d.read - start; n != int64(extLen) {
+		return fmt.Errorf("hpack: type id ext is %d bytes, decoded %d", extLen, n)
+	}
+	return nil
+}
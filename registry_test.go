@@ -0,0 +1,142 @@
+package hpack_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type regPacket interface{ packetName() string }
+
+type regLogin struct {
+	User string `msgpack:"user"`
+}
+
+type regMove struct {
+	X, Y int16
+}
+
+func (*regLogin) packetName() string { return "login" }
+func (regMove) packetName() string   { return "move" }
+
+type regEnvelope struct {
+	Seq     int                    `msgpack:"seq"`
+	Payload regPacket              `msgpack:"payload"`
+	List    []interface{}          `msgpack:"list"`
+	ByName  map[string]interface{} `msgpack:"byName"`
+}
+
+func init() {
+	hpack.RegisterType[*regLogin](101)
+	hpack.RegisterType[regMove](102)
+}
+
+func TestRegisterTypeRoundTrip(t *testing.T) {
+	in := regEnvelope{
+		Seq:     1,
+		Payload: &regLogin{User: "kim"},
+		List:    []interface{}{regMove{X: 1, Y: -2}, "s", &regLogin{User: "lee"}},
+		ByName:  map[string]interface{}{"move": regMove{X: 3}},
+	}
+	b, err := hpack.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out regEnvelope
+	if err := hpack.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("got %#v, want %#v", out, in)
+	}
+
+	// 기존 포인터 값이 있어도 등록된 타입의 새 값으로 채운다.
+	out = regEnvelope{Payload: &regLogin{User: "old"}}
+	if err := hpack.Unmarshal(b, &out); err != nil || out.Payload.(*regLogin).User != "kim" {
+		t.Fatalf("got %#v, err %v", out.Payload, err)
+	}
+}
+
+func TestRegisterTypeExtID(t *testing.T) {
+	b, err := hpack.Marshal([]interface{}{regMove{X: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fixarray(1), ext 헤더(fixext 또는 ext8) 다음이 ext 타입
+	i := 2
+	if b[1] == hpack.Ext8 {
+		i = 3
+	}
+	if extID := int8(b[i]); extID < 0 {
+		t.Fatalf("ext id = %d, want non-negative", extID)
+	}
+}
+
+func registeredExt(t *testing.T, id byte, v interface{}, extra []byte, lenDelta int) []byte {
+	t.Helper()
+	val, err := hpack.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := append(append([]byte{id}, val...), extra...)
+	return append([]byte{hpack.Ext8, byte(len(payload) + lenDelta), 127}, payload...)
+}
+
+func TestRegisterTypeExtLen(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		wantErr string
+	}{
+		{"ok", registeredExt(t, 102, regMove{X: 1}, nil, 0), ""},
+		{"trailing payload", registeredExt(t, 102, regMove{X: 1}, []byte{0xc0}, 0), "type id ext"},
+		{"short ext len", append(registeredExt(t, 102, regMove{X: 1}, nil, -1), 0xc0), "type id ext"},
+		{"unregistered", registeredExt(t, 120, regMove{X: 1}, nil, 0), "unregistered type id 120"},
+		{"empty", []byte{hpack.Ext8, 0, 127}, "empty type id ext"},
+		{"struct trailing payload", registeredExt(t, 0, &regLogin{User: "kim"}, []byte{0xc0}, 0), "type id ext"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			dec := hpack.NewDecoder(bytes.NewReader(tt.b))
+			err := dec.Decode(&v)
+			if tt.wantErr == "" {
+				if err != nil || v != (regMove{X: 1}) {
+					t.Fatalf("got %#v, err %v", v, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got err %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterTypePanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"interface type", func() { hpack.RegisterType[regPacket](103) }},
+		{"id taken", func() { hpack.RegisterType[regEnvelope](101) }},
+		{"type taken", func() { hpack.RegisterType[regMove](104) }},
+		{"reserved type id", func() { hpack.RegisterType[regEnvelope](0) }},
+		{"reserved ext id", func() {
+			hpack.RegisterExtDecoder(127, regMove{}, func(*hpack.Decoder, reflect.Value, int) error { return nil })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("no panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
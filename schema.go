@@ -0,0 +1,220 @@
+package hpack
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Schema : 해시 기반 struct 타입의 스키마 기술자
+//
+// JSON과 hpack으로 직렬화할 수 있으며, 다른 언어의 클라이언트가 CRC32 + fold 로직을
+// 직접 구현하지 않고 필드 해시를 그대로 사용할 수 있도록 한다.
+type Schema struct {
+	Root  string          `json:"root" msgpack:"root"`   // 최상위 struct 이름
+	Types []*StructSchema `json:"types" msgpack:"types"` // 최상위 struct와 참조된 모든 struct
+}
+
+// StructSchema : struct 타입 하나의 스키마
+type StructSchema struct {
+	Name    string        `json:"name" msgpack:"name"`                           // Go 타입명 (reflect.Type.String)
+	Hasher  string        `json:"hasher" msgpack:"hasher"`                       // 필드명 해시 함수 (FieldHasher.Name)
+	AsArray bool          `json:"asArray,omitempty" msgpack:"asArray,omitempty"` // 필드명 없이 배열로 인코딩 (Fields 순서)
+	Fields  []FieldSchema `json:"fields" msgpack:"fields"`
+}
+
+// FieldSchema : 직렬화되는 필드 하나의 스키마
+type FieldSchema struct {
+	GoName    string            `json:"goName" msgpack:"goName"`                           // Go 필드명
+	Name      string            `json:"name" msgpack:"name"`                               // 태그명(해시 대상 문자열)
+	Hash      uint32            `json:"hash" msgpack:"hash"`                               // 할당된 해시
+	Size      FieldNameSizeFlag `json:"size" msgpack:"size"`                               // 할당된 해시 크기 Flag
+	Pinned    bool              `json:"pinned,omitempty" msgpack:"pinned,omitempty"`       // 태그로 고정된 해시
+	GoType    string            `json:"goType" msgpack:"goType"`                           // Go 필드 타입
+	Kind      string            `json:"kind" msgpack:"kind"`                               // 와이어 타입 계열 (schemaKind 참고)
+	OmitEmpty bool              `json:"omitEmpty,omitempty" msgpack:"omitEmpty,omitempty"` // omitempty 여부
+	Inline    string            `json:"inline,omitempty" msgpack:"inline,omitempty"`       // 인라인된 embedded struct 경로
+	Ref       string            `json:"ref,omitempty" msgpack:"ref,omitempty"`             // 참조하는 struct 이름 (Schema.Types)
+}
+
+// Type : 이름으로 struct 스키마 검색
+func (s *Schema) Type(name string) *StructSchema {
+	for _, t := range s.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Field : 태그명으로 필드 스키마 검색
+func (s *StructSchema) Field(name string) *FieldSchema {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i]
+		}
+	}
+	return nil
+}
+
+// SchemaOptions : 스키마 생성(SchemaOfOptions)과 검증(ValidateWith)에 사용할 Encoder/Decoder 설정
+type SchemaOptions struct {
+	PrimaryTag  string      // SetStructTags의 primary (비어 있으면 msgpack)
+	FallbackTag string      // SetStructTags의 fallback (SetCustomStructTag)
+	Hasher      FieldHasher // SetFieldHasher의 hasher (nil이면 기본값)
+}
+
+func (opts SchemaOptions) tags() structTags {
+	return structTags{primary: opts.PrimaryTag, fallback: opts.FallbackTag}
+}
+
+// SchemaOf : typ(또는 typ의 포인터가 가리키는 struct)의 스키마를 structs.Fields로부터 생성
+func SchemaOf(typ reflect.Type) (*Schema, error) {
+	return SchemaOfOptions(typ, SchemaOptions{})
+}
+
+// SchemaOfHasher : SchemaOf와 같지만 Encoder/Decoder의 SetFieldHasher와 같은 hasher를 기준으로 생성
+func SchemaOfHasher(typ reflect.Type, hasher FieldHasher) (*Schema, error) {
+	return SchemaOfOptions(typ, SchemaOptions{Hasher: hasher})
+}
+
+// SchemaOfOptions : SchemaOf와 같지만 Encoder/Decoder와 같은 태그 키, hasher를 기준으로 생성
+func SchemaOfOptions(typ reflect.Type, opts SchemaOptions) (*Schema, error) {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || !isStructType(typ) {
+		return nil, fmt.Errorf("hpack: SchemaOf(non-struct %v)", typ)
+	}
+
+	b := schemaBuilder{
+		schema:  &Schema{Root: typ.String()},
+		hasher:  opts.Hasher,
+		tags:    opts.tags(),
+		visited: make(map[reflect.Type]struct{}),
+	}
+	if err := b.addStruct(typ); err != nil {
+		return nil, err
+	}
+	return b.schema, nil
+}
+
+type schemaBuilder struct {
+	schema  *Schema
+	hasher  FieldHasher
+	tags    structTags
+	visited map[reflect.Type]struct{}
+}
+
+func (b *schemaBuilder) addStruct(typ reflect.Type) error {
+	if _, ok := b.visited[typ]; ok {
+		return nil
+	}
+	b.visited[typ] = struct{}{}
+
+	fs := structs.load(typ, b.hasher, b.tags)
+	if fs.err != nil {
+		return fs.err
+	}
+	ss := &StructSchema{
+		Name:    typ.String(),
+		Hasher:  fs.Hasher.Name(),
+		AsArray: fs.AsArray,
+		Fields:  make([]FieldSchema, 0, len(fs.List)),
+	}
+	b.schema.Types = append(b.schema.Types, ss)
+
+	var refs []reflect.Type
+	for _, f := range fs.List {
+		fd := FieldSchema{
+			GoName:    f.goName,
+			Name:      f.fieldName.name,
+			Hash:      f.fieldName.hash32,
+			Size:      f.fieldName.size,
+			Pinned:    f.fieldName.pinned,
+			GoType:    f.typ.String(),
+			Kind:      schemaKind(f.typ),
+			OmitEmpty: f.omitEmpty,
+			Inline:    f.inline,
+		}
+		if ref := structRef(f.typ); ref != nil {
+			fd.Ref = ref.String()
+			refs = append(refs, ref)
+		}
+		ss.Fields = append(ss.Fields, fd)
+	}
+
+	for _, ref := range refs {
+		if err := b.addStruct(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaKind : Go 타입의 와이어 타입 계열
+// int/uint는 같은 계열(int)로 취급한다.
+func schemaKind(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		return "time"
+	}
+	if hasCustomEncoder(typ) {
+		return "custom"
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "array"
+	case reflect.Map:
+		return "map"
+	case reflect.Struct:
+		if isStructType(typ) {
+			return "struct"
+		}
+		return "custom"
+	case reflect.Interface:
+		return "any"
+	}
+	return "unsupported"
+}
+
+// structRef : 필드 타입이 (포인터, 슬라이스, 배열, 맵 값을 거쳐) 참조하는 struct 타입
+func structRef(typ reflect.Type) reflect.Type {
+	for {
+		if isStructType(typ) {
+			return typ
+		}
+		if hasCustomEncoder(typ) {
+			return nil
+		}
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			typ = typ.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
+// isStructType : hpack의 struct 포맷(해시 필드명)으로 인코딩되는 타입인지 여부
+func isStructType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct &&
+		reflect.ValueOf(getEncoder(typ)).Pointer() == encodeStructValuePtr
+}
@@ -0,0 +1,252 @@
+package hpack
+
+import "fmt"
+
+// SchemaChangeKind : 두 스키마 사이의 변경 종류
+type SchemaChangeKind byte
+
+const (
+	ChangeHashChanged    SchemaChangeKind = iota + 1 // 같은 필드의 해시가 변경됨
+	ChangeWidthChanged                               // 같은 필드의 해시 크기가 변경됨
+	ChangeFieldRemoved                               // 필드가 제거됨
+	ChangeKindChanged                                // 필드의 와이어 타입 계열이 변경됨 (예: string -> int)
+	ChangeHashReused                                 // 새 필드의 해시가 제거된 필드의 해시와 같음
+	ChangeFieldAdded                                 // 필드가 추가됨 (호환)
+	ChangeAsArrayChanged                             // struct의 배열 인코딩(as_array) 여부가 변경됨
+	ChangeFieldMoved                                 // as_array struct의 같은 위치에 다른 필드가 있음 (순서 변경, 중간 추가/제거)
+	ChangeHasherChanged                              // struct의 필드 해시 함수가 변경됨 (모든 필드의 해시가 바뀜)
+)
+
+func (k SchemaChangeKind) ToString() string {
+	switch k {
+	case ChangeHashChanged:
+		return "hash changed"
+	case ChangeWidthChanged:
+		return "width changed"
+	case ChangeFieldRemoved:
+		return "field removed"
+	case ChangeKindChanged:
+		return "kind changed"
+	case ChangeHashReused:
+		return "hash reused"
+	case ChangeFieldAdded:
+		return "field added"
+	case ChangeAsArrayChanged:
+		return "as_array changed"
+	case ChangeFieldMoved:
+		return "field moved"
+	case ChangeHasherChanged:
+		return "hasher changed"
+	}
+
+	return "Unknown"
+}
+
+// SchemaChange : 두 스키마 사이의 변경 하나
+type SchemaChange struct {
+	Kind  SchemaChangeKind
+	Type  string       // 이전 스키마의 struct 이름
+	Field string       // 필드명(태그명). struct 단위 변경이면 빈 문자열
+	Old   *FieldSchema // 이전 필드 (ChangeFieldAdded, struct 단위 변경이면 nil)
+	New   *FieldSchema // 새 필드 (ChangeFieldRemoved, struct 단위 변경이면 nil)
+}
+
+// Breaking : 이미 배포된 클라이언트와 와이어 호환성이 깨지는 변경인지 여부
+func (c SchemaChange) Breaking() bool {
+	return c.Kind != ChangeFieldAdded
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case ChangeHashChanged:
+		return fmt.Sprintf("%s: %s.%s %#x -> %#x", c.Kind.ToString(), c.Type, c.Field, c.Old.Hash, c.New.Hash)
+	case ChangeWidthChanged:
+		return fmt.Sprintf("%s: %s.%s %s -> %s", c.Kind.ToString(), c.Type, c.Field, c.Old.Size.ToString(), c.New.Size.ToString())
+	case ChangeKindChanged:
+		return fmt.Sprintf("%s: %s.%s %s -> %s", c.Kind.ToString(), c.Type, c.Field, c.Old.Kind, c.New.Kind)
+	case ChangeFieldMoved:
+		return fmt.Sprintf("%s: %s.%s is now %s at the same position", c.Kind.ToString(), c.Type, c.Old.Name, c.New.Name)
+	case ChangeAsArrayChanged, ChangeHasherChanged:
+		return fmt.Sprintf("%s: %s", c.Kind.ToString(), c.Type)
+	case ChangeHashReused:
+		return fmt.Sprintf("%s: %s.%s uses hash %#x of removed field %s", c.Kind.ToString(), c.Type, c.Field, c.New.Hash, c.Old.Name)
+	}
+	return fmt.Sprintf("%s: %s.%s", c.Kind.ToString(), c.Type, c.Field)
+}
+
+// DiffSchema : 이전 스키마(old)와 새 스키마(new)를 비교하여 변경 목록을 반환
+//
+// 최상위 struct부터 필드의 참조(Ref)를 따라가며 비교하므로 Go 타입명이 바뀌어도 구조가 같으면 같은 타입으로 본다.
+// 필드는 태그명으로 짝을 짓고, 태그명이 바뀌었더라도 고정 해시(pinned)가 같으면 같은 필드로 본다.
+// as_array struct는 필드를 위치로 짝을 지으며, 끝에 추가하는 것 외의 변경은 모두 호환되지 않는다.
+// Root나 Ref가 가리키는 struct가 Types에 없으면(잘못된 스키마) 에러를 반환한다.
+func DiffSchema(old, new *Schema) ([]SchemaChange, error) {
+	d := schemaDiff{
+		old:     old,
+		new:     new,
+		visited: make(map[[2]string]struct{}),
+	}
+	d.diff(old.Root, new.Root)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.changes, nil
+}
+
+// BreakingChanges : 변경 목록 중 와이어 호환성이 깨지는 변경만 반환
+func BreakingChanges(changes []SchemaChange) []SchemaChange {
+	var breaking []SchemaChange
+	for _, c := range changes {
+		if c.Breaking() {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+type schemaDiff struct {
+	old, new *Schema
+	visited  map[[2]string]struct{}
+	changes  []SchemaChange
+	err      error
+}
+
+func (d *schemaDiff) add(kind SchemaChangeKind, typ string, old, new *FieldSchema) {
+	c := SchemaChange{Kind: kind, Type: typ, Old: old, New: new}
+	if new != nil {
+		c.Field = new.Name
+	} else {
+		c.Field = old.Name
+	}
+	d.changes = append(d.changes, c)
+}
+
+// diff : 이름으로 양쪽 struct 스키마를 찾아 비교
+func (d *schemaDiff) diff(oldName, newName string) {
+	if d.err != nil {
+		return
+	}
+	oldType := d.old.Type(oldName)
+	if oldType == nil {
+		d.err = fmt.Errorf("hpack: old schema has no type %q", oldName)
+		return
+	}
+	newType := d.new.Type(newName)
+	if newType == nil {
+		d.err = fmt.Errorf("hpack: new schema has no type %q", newName)
+		return
+	}
+	d.diffStruct(oldType, newType)
+}
+
+func (d *schemaDiff) diffStruct(oldType, newType *StructSchema) {
+	key := [2]string{oldType.Name, newType.Name}
+	if _, ok := d.visited[key]; ok {
+		return
+	}
+	d.visited[key] = struct{}{}
+
+	if oldType.AsArray != newType.AsArray {
+		d.changes = append(d.changes, SchemaChange{Kind: ChangeAsArrayChanged, Type: oldType.Name})
+	} else if oldType.AsArray {
+		d.diffArray(oldType, newType)
+		return
+	}
+
+	// 해시 함수가 바뀌면 모든 필드의 해시가 바뀌므로 필드별 해시 변경 대신 하나로 보고
+	hasherChanged := oldType.Hasher != newType.Hasher
+	if hasherChanged {
+		d.changes = append(d.changes, SchemaChange{Kind: ChangeHasherChanged, Type: oldType.Name})
+	}
+
+	matched := make(map[*FieldSchema]struct{}, len(newType.Fields))
+	var removed []*FieldSchema
+
+	for i := range oldType.Fields {
+		of := &oldType.Fields[i]
+		nf := matchField(oldType, newType, of)
+		if nf == nil {
+			removed = append(removed, of)
+			d.add(ChangeFieldRemoved, oldType.Name, of, nil)
+			continue
+		}
+		matched[nf] = struct{}{}
+
+		switch {
+		case hasherChanged:
+		case of.Size != nf.Size:
+			d.add(ChangeWidthChanged, oldType.Name, of, nf)
+		case of.Hash != nf.Hash:
+			d.add(ChangeHashChanged, oldType.Name, of, nf)
+		}
+		d.diffValue(oldType, of, nf)
+	}
+
+	for i := range newType.Fields {
+		nf := &newType.Fields[i]
+		if _, ok := matched[nf]; ok {
+			continue
+		}
+
+		reused := false
+		for _, of := range removed {
+			if hasherChanged {
+				break
+			}
+			if of.Hash == nf.Hash {
+				d.changes = append(d.changes, SchemaChange{
+					Kind: ChangeHashReused, Type: oldType.Name, Field: nf.Name, Old: of, New: nf,
+				})
+				reused = true
+			}
+		}
+		if !reused {
+			d.add(ChangeFieldAdded, oldType.Name, nil, nf)
+		}
+	}
+}
+
+// diffArray : as_array struct는 필드명(해시) 없이 위치로 인코딩되므로 같은 위치의 필드끼리 비교
+func (d *schemaDiff) diffArray(oldType, newType *StructSchema) {
+	for i := range oldType.Fields {
+		of := &oldType.Fields[i]
+		if i >= len(newType.Fields) {
+			d.add(ChangeFieldRemoved, oldType.Name, of, nil)
+			continue
+		}
+		nf := &newType.Fields[i]
+		if of.Name != nf.Name {
+			d.add(ChangeFieldMoved, oldType.Name, of, nf)
+		}
+		d.diffValue(oldType, of, nf)
+	}
+	for i := len(oldType.Fields); i < len(newType.Fields); i++ {
+		d.add(ChangeFieldAdded, oldType.Name, nil, &newType.Fields[i])
+	}
+}
+
+// diffValue : 짝지은 두 필드의 값 타입 비교
+func (d *schemaDiff) diffValue(oldType *StructSchema, of, nf *FieldSchema) {
+	if of.Kind != nf.Kind {
+		d.add(ChangeKindChanged, oldType.Name, of, nf)
+	}
+	if of.Ref != "" && nf.Ref != "" {
+		d.diff(of.Ref, nf.Ref)
+	}
+}
+
+func matchField(oldType, newType *StructSchema, of *FieldSchema) *FieldSchema {
+	if nf := newType.Field(of.Name); nf != nil {
+		return nf
+	}
+	for i := range newType.Fields {
+		nf := &newType.Fields[i]
+		if oldType.Field(nf.Name) != nil {
+			continue
+		}
+		if (of.Pinned || nf.Pinned) && nf.Hash == of.Hash && nf.Size == of.Size {
+			return nf
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package hpack_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+func changeKinds(changes []hpack.SchemaChange) []hpack.SchemaChangeKind {
+	kinds := make([]hpack.SchemaChangeKind, 0, len(changes))
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind)
+	}
+	return kinds
+}
+
+type dSub struct {
+	Level int `msgpack:"level"`
+}
+
+type dSubKind struct {
+	Level string `msgpack:"level"`
+}
+
+type dV1 struct {
+	ID   int    `msgpack:"id"`
+	Name string `msgpack:"name"`
+	Old  int    `msgpack:"old"`
+	Sub  dSub   `msgpack:"sub"`
+}
+
+type dV1Added struct {
+	ID    int    `msgpack:"id"`
+	Name  string `msgpack:"name"`
+	Old   int    `msgpack:"old"`
+	Sub   dSub   `msgpack:"sub"`
+	Extra int    `msgpack:"extra"`
+}
+
+type dV1Removed struct {
+	ID   int    `msgpack:"id"`
+	Name string `msgpack:"name"`
+	Sub  dSub   `msgpack:"sub"`
+}
+
+type dV1Kind struct {
+	ID   int      `msgpack:"id"`
+	Name int      `msgpack:"name"`
+	Old  int      `msgpack:"old"`
+	Sub  dSubKind `msgpack:"sub"`
+}
+
+type dWidth struct {
+	F2 int `msgpack:"f2"`
+	CC int `msgpack:"cc"`
+}
+
+type dWidthAdded struct {
+	F2  int `msgpack:"f2"`
+	CC  int `msgpack:"cc"`
+	F50 int `msgpack:"f50"` // f2와 1B 해시가 같아 f2가 2B로 늘어남
+}
+
+type dPinned struct {
+	HP int `msgpack:"hp,hash=0x3a"`
+}
+
+type dPinnedRenamed struct {
+	Health int `msgpack:"health,hash=0x3a"`
+}
+
+type dPinnedReused struct {
+	MP int `msgpack:"mp,hash=0x3a"`
+}
+
+func TestDiffSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new interface{}
+		kinds    []hpack.SchemaChangeKind
+	}{
+		{"same", dV1{}, dV1{}, []hpack.SchemaChangeKind{}},
+		{"added", dV1{}, dV1Added{}, []hpack.SchemaChangeKind{hpack.ChangeFieldAdded}},
+		{"removed", dV1{}, dV1Removed{}, []hpack.SchemaChangeKind{hpack.ChangeFieldRemoved}},
+		{"kind", dV1{}, dV1Kind{}, []hpack.SchemaChangeKind{hpack.ChangeKindChanged, hpack.ChangeKindChanged}},
+		{"width", dWidth{}, dWidthAdded{}, []hpack.SchemaChangeKind{hpack.ChangeWidthChanged, hpack.ChangeFieldAdded}},
+		{"pinned rename", dPinned{}, dPinnedRenamed{}, []hpack.SchemaChangeKind{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := hpack.DiffSchema(schemaOf(t, tt.old), schemaOf(t, tt.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kinds := changeKinds(changes); !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Fatalf("changes = %v, want kinds %v", changes, tt.kinds)
+			}
+			for _, c := range changes {
+				if c.Breaking() != (c.Kind != hpack.ChangeFieldAdded) {
+					t.Fatalf("%v: breaking = %v", c, c.Breaking())
+				}
+			}
+		})
+	}
+}
+
+func TestDiffSchemaHashReused(t *testing.T) {
+	old := schemaOf(t, dPinned{})
+	new := schemaOf(t, dPinnedReused{})
+	// 고정 해시가 같으면 이름이 바뀐 같은 필드로 보므로, 고정하지 않은 필드로 바꿔 재사용을 만든다.
+	old.Types[0].Fields[0].Pinned = false
+	new.Types[0].Fields[0].Pinned = false
+
+	changes, err := hpack.DiffSchema(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []hpack.SchemaChangeKind{hpack.ChangeFieldRemoved, hpack.ChangeHashReused}
+	if kinds := changeKinds(changes); !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("changes = %v, want kinds %v", changes, want)
+	}
+}
+
+type dPosSwap struct {
+	_hpack struct{} `hpack:",as_array"`
+	Y, X   float32
+}
+
+type dPosInsert struct {
+	_hpack  struct{} `hpack:",as_array"`
+	X, Z, Y float32
+}
+
+type dPosRemoved struct {
+	_hpack struct{} `hpack:",as_array"`
+	X      float32
+}
+
+type dPosKind struct {
+	_hpack struct{} `hpack:",as_array"`
+	X      string
+	Y      float32
+}
+
+type dPosMap struct {
+	X, Y float32
+}
+
+func TestDiffSchemaAsArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new interface{}
+		kinds    []hpack.SchemaChangeKind
+		breaking bool
+	}{
+		{"same", dPos{}, dPos{}, []hpack.SchemaChangeKind{}, false},
+		{"append", dPos{}, dPosAppend{}, []hpack.SchemaChangeKind{hpack.ChangeFieldAdded}, false},
+		{"swap", dPos{}, dPosSwap{}, []hpack.SchemaChangeKind{hpack.ChangeFieldMoved, hpack.ChangeFieldMoved}, true},
+		{"insert", dPos{}, dPosInsert{}, []hpack.SchemaChangeKind{hpack.ChangeFieldMoved, hpack.ChangeFieldAdded}, true},
+		{"remove last", dPos{}, dPosRemoved{}, []hpack.SchemaChangeKind{hpack.ChangeFieldRemoved}, true},
+		{"kind", dPos{}, dPosKind{}, []hpack.SchemaChangeKind{hpack.ChangeKindChanged}, true},
+		{"to map", dPos{}, dPosMap{}, []hpack.SchemaChangeKind{hpack.ChangeAsArrayChanged}, true},
+		{"to array", dPosMap{}, dPos{}, []hpack.SchemaChangeKind{hpack.ChangeAsArrayChanged}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := hpack.DiffSchema(schemaOf(t, tt.old), schemaOf(t, tt.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kinds := changeKinds(changes); !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Fatalf("changes = %v, want kinds %v", changes, tt.kinds)
+			}
+			if breaking := len(hpack.BreakingChanges(changes)) > 0; breaking != tt.breaking {
+				t.Fatalf("breaking = %v, changes %v", breaking, changes)
+			}
+			for _, c := range changes {
+				if c.String() == "" {
+					t.Fatal("empty change string")
+				}
+			}
+		})
+	}
+}
+
+func TestDiffSchemaHasher(t *testing.T) {
+	old := schemaOf(t, dV1{})
+	new, err := hpack.SchemaOfHasher(reflect.TypeOf(dV1Removed{}), hpack.FNV1aHasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := hpack.DiffSchema(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 필드별 해시 변경 없이 해시 함수 변경 하나와 제거된 필드만 보고한다. (dSub도 해시 함수가 바뀜)
+	want := []hpack.SchemaChangeKind{hpack.ChangeHasherChanged, hpack.ChangeFieldRemoved, hpack.ChangeHasherChanged}
+	if kinds := changeKinds(changes); !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("changes = %v, want kinds %v", changes, want)
+	}
+	if !changes[0].Breaking() {
+		t.Fatal("hasher change is not breaking")
+	}
+}
+
+func TestDiffSchemaMissingType(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(old, new *hpack.Schema)
+	}{
+		{"old root", func(old, new *hpack.Schema) { old.Root = "missing" }},
+		{"new root", func(old, new *hpack.Schema) { new.Root = "missing" }},
+		{"old ref", func(old, new *hpack.Schema) { old.Types = old.Types[:1] }},
+		{"new ref", func(old, new *hpack.Schema) { new.Types[0].Fields[3].Ref = "missing" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := schemaOf(t, dV1{}), schemaOf(t, dV1{})
+			tt.modify(old, new)
+			if changes, err := hpack.DiffSchema(old, new); err == nil {
+				t.Fatalf("changes = %v, want error", changes)
+			}
+		})
+	}
+}
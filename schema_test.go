@@ -0,0 +1,148 @@
+package hpack_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boldplaygames/hpack"
+)
+
+func schemaOf(t *testing.T, v interface{}) *hpack.Schema {
+	t.Helper()
+	s, err := hpack.SchemaOf(reflect.TypeOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+type sItem struct {
+	Count uint8 `msgpack:"count"`
+}
+
+type sBase struct {
+	Level int `msgpack:"level"`
+}
+
+type sPlayer struct {
+	sBase `msgpack:",inline"`
+	Name  string            `msgpack:"name,omitempty"`
+	Score float64           `msgpack:"score"`
+	Items []*sItem          `msgpack:"items"`
+	ByID  map[int]sItem     `msgpack:"byId"`
+	At    time.Time         `msgpack:"at"`
+	Any   interface{}       `msgpack:"any"`
+	Raw   []byte            `msgpack:"raw"`
+	Tags  map[string]string `msgpack:"tags"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := schemaOf(t, &sPlayer{})
+	if s.Root != "hpack_test.sPlayer" || len(s.Types) != 2 || s.Type("hpack_test.sItem") == nil {
+		t.Fatalf("schema = %+v", s)
+	}
+
+	root := s.Type(s.Root)
+	if root.Hasher != hpack.CRC32IEEEHasher.Name() || root.AsArray {
+		t.Fatalf("root = %+v", root)
+	}
+	tests := []struct {
+		name, kind, ref, inline string
+		omitEmpty               bool
+	}{
+		{"level", "int", "", "sBase", false},
+		{"name", "string", "", "", true},
+		{"score", "float", "", "", false},
+		{"items", "array", "hpack_test.sItem", "", false},
+		{"byId", "map", "hpack_test.sItem", "", false},
+		{"at", "time", "", "", false},
+		{"any", "any", "", "", false},
+		{"raw", "bytes", "", "", false},
+		{"tags", "map", "", "", false},
+	}
+	if len(root.Fields) != len(tests) {
+		t.Fatalf("fields = %+v", root.Fields)
+	}
+	for _, tt := range tests {
+		f := root.Field(tt.name)
+		if f == nil || f.Kind != tt.kind || f.Ref != tt.ref || f.Inline != tt.inline || f.OmitEmpty != tt.omitEmpty {
+			t.Fatalf("%s = %+v", tt.name, f)
+		}
+	}
+
+	// 스키마의 해시는 인코딩된 키와 같다.
+	b, err := hpack.Marshal(&sItem{Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, keys := structKeys(t, b); keys[s.Type("hpack_test.sItem").Field("count").Hash] != 1 {
+		t.Fatalf("encoded % x", b)
+	}
+
+	if _, err := hpack.SchemaOf(reflect.TypeOf(1)); err == nil {
+		t.Fatal("SchemaOf(int): want error")
+	}
+}
+
+func TestSchemaSerialize(t *testing.T) {
+	s := schemaOf(t, sPlayer{})
+
+	j, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON hpack.Schema
+	if err := json.Unmarshal(j, &fromJSON); err != nil || !reflect.DeepEqual(&fromJSON, s) {
+		t.Fatalf("json: got %+v, err %v", fromJSON, err)
+	}
+
+	b, err := hpack.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromHpack hpack.Schema
+	if err := hpack.Unmarshal(b, &fromHpack); err != nil || !reflect.DeepEqual(&fromHpack, s) {
+		t.Fatalf("hpack: got %+v, err %v", fromHpack, err)
+	}
+}
+
+type sTagged struct {
+	ID   int    `hpack:"id" json:"ignored"`
+	Name string `json:"name"`
+	Skip int    `hpack:"-"`
+}
+
+func TestSchemaOfOptionsTags(t *testing.T) {
+	opts := hpack.SchemaOptions{PrimaryTag: "hpack", FallbackTag: "json"}
+	s, err := hpack.SchemaOfOptions(reflect.TypeOf(&sTagged{}), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range s.Types[0].Fields {
+		names = append(names, f.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"id", "name"}) {
+		t.Fatalf("fields = %v, want [id name]", names)
+	}
+
+	// 스키마의 해시는 같은 태그를 지정한 인코더의 출력과 일치해야 한다.
+	v := decodeAny(t, &sTagged{ID: 1, Name: "kim", Skip: 2}, func(enc *hpack.Encoder) {
+		enc.SetStructTags(opts.PrimaryTag, opts.FallbackTag)
+	})
+	m, _ := v.(map[uint32]interface{})
+	if len(m) != 2 || m[s.Types[0].Field("id").Hash] == nil || m[s.Types[0].Field("name").Hash] != "kim" {
+		t.Fatalf("encoded %v, schema %+v", m, s.Types[0].Fields)
+	}
+
+	// 기본 설정은 msgpack 태그(없으면 Go 필드명)를 읽는다.
+	s, err = hpack.SchemaOf(reflect.TypeOf(sTagged{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Types[0].Field("ID") == nil || s.Types[0].Field("Skip") == nil {
+		t.Fatalf("default fields = %+v", s.Types[0].Fields)
+	}
+}
@@ -0,0 +1,283 @@
+package hpack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// ErrNonCanonical : UseStrict에서 입력을 거부했을 때. errors.Is(err, ErrNonCanonical)로 확인
+var ErrNonCanonical = errors.New("hpack: non-canonical input")
+
+// StrictError : UseStrict에서 입력을 거부했을 때 반환되는 에러
+type StrictError struct {
+	Reason string // 거부한 이유 (예: "duplicate map key")
+}
+
+func (e *StrictError) Error() string {
+	return "hpack: strict: " + e.Reason
+}
+
+func (e *StrictError) Is(target error) bool {
+	return target == ErrNonCanonical
+}
+
+func strictErrorf(format string, v ...interface{}) error {
+	return &StrictError{Reason: fmt.Sprintf(format, v...)}
+}
+
+// UseStrict : 같은 값을 나타내는 바이트가 하나뿐이도록 Encoder가 기록하는 형식만 허용 (서명 검증 등)
+//
+// struct의 중복 필드와 map의 중복 키, fields.List 순서가 아닌 struct 필드,
+// encodeFieldLen과 다른 해시 크기 플래그(균일 크기와 FieldNameSizeFlagMixed 모두),
+// 가장 짧은 형식으로 기록되지 않은 정수와 길이, nil이 될 수 없는 값(bool, 숫자, 문자열, struct, 배열)의 Nil,
+// float32는 Float, float64는 Double이 아닌 실수를 에러로 반환한다.
+// 정수는 Encoder.UseCompactInts(기본값)로 기록해야 하고, Encoder.UseCompactFloats로 기록한 실수는 거부한다.
+func (d *Decoder) UseStrict(on bool) {
+	if on {
+		d.flags |= strictFlag
+	} else {
+		d.flags &= ^strictFlag
+	}
+}
+
+func (d *Decoder) strict() bool {
+	return d.flags&strictFlag != 0
+}
+
+// checkShortestInt : 정수 코드 c로 읽은 값 n(부호 있는 코드이면 int64로 변환한 비트)이 EncodeInt/EncodeUint와 같은 형식인지 확인
+func (d *Decoder) checkShortestInt(c byte, n uint64) error {
+	if !d.strict() || msgpcode.IsFixedNum(c) {
+		return nil
+	}
+	if c == msgpcode.Nil {
+		return errStrictNil("integer")
+	}
+
+	var want byte
+	switch c {
+	case msgpcode.Int8, msgpcode.Int16, msgpcode.Int32, msgpcode.Int64:
+		want = intCode(int64(n))
+	default:
+		want = uintCode(n)
+	}
+	if want != c {
+		return strictErrorf("integer encoded with code=%x, shortest is %x", c, want)
+	}
+	return nil
+}
+
+// intCode : EncodeInt가 n을 기록하는 코드 (fixnum이면 값 자체)
+func intCode(n int64) byte {
+	switch {
+	case n >= 0:
+		return uintCode(uint64(n))
+	case n >= int64(int8(NegFixedNumLow)):
+		return byte(n)
+	case n >= math.MinInt8:
+		return msgpcode.Int8
+	case n >= math.MinInt16:
+		return msgpcode.Int16
+	case n >= math.MinInt32:
+		return msgpcode.Int32
+	}
+	return msgpcode.Int64
+}
+
+// uintCode : EncodeUint가 n을 기록하는 코드 (fixnum이면 값 자체)
+func uintCode(n uint64) byte {
+	switch {
+	case n <= math.MaxInt8:
+		return byte(n)
+	case n <= math.MaxUint8:
+		return msgpcode.Uint8
+	case n <= math.MaxUint16:
+		return msgpcode.Uint16
+	case n <= math.MaxUint32:
+		return msgpcode.Uint32
+	}
+	return msgpcode.Uint64
+}
+
+// checkFloatCode : 실수 코드 c가 Encoder가 기록하는 코드 want(float32는 Float, float64는 Double)인지 확인
+func (d *Decoder) checkFloatCode(c, want byte) error {
+	if d.strict() && c != want {
+		return strictErrorf("float encoded with code=%x, want %x", c, want)
+	}
+	return nil
+}
+
+// checkNextNotNil : 다음 값이 nil이 될 수 없는 typ인데 Nil 코드로 기록되지 않았는지 확인
+func (d *Decoder) checkNextNotNil(typ string) error {
+	if d.strict() && d.hasNilCode() {
+		return errStrictNil(typ)
+	}
+	return nil
+}
+
+func errStrictNil(typ string) error {
+	return strictErrorf("nil encoded for %s", typ)
+}
+
+// checkShortestLen : 길이 코드 c로 읽은 길이 n이 Encoder와 같은 형식인지 확인
+func (d *Decoder) checkShortestLen(c byte, n int) error {
+	if !d.strict() {
+		return nil
+	}
+
+	var ok bool
+	switch c {
+	case msgpcode.Array16, msgpcode.Map16:
+		ok = n >= 16
+	case msgpcode.Str8:
+		ok = n >= 32
+	case msgpcode.Str16, msgpcode.Bin16, msgpcode.Ext16:
+		ok = n > math.MaxUint8
+	case msgpcode.Array32, msgpcode.Map32, msgpcode.Str32, msgpcode.Bin32, msgpcode.Ext32:
+		ok = n > math.MaxUint16
+	case msgpcode.Ext8:
+		ok = n != 1 && n != 2 && n != 4 && n != 8 && n != 16
+	default:
+		ok = true
+	}
+	if !ok {
+		return strictErrorf("length %d encoded with code=%x", n, c)
+	}
+	return nil
+}
+
+// checkFieldLen : struct의 해시 크기 플래그가 struct 필드(alias 포함) 해시의 최대 크기보다 크지 않은지 확인
+// alias는 이전 struct가 기록한 크기일 수 있으므로 포함한다.
+func (d *Decoder) checkFieldLen(fs *fields, fieldLen FieldNameSizeFlag) error {
+	if !d.strict() || fieldLen.ToSize() <= 0 {
+		return nil
+	}
+	max := maxFieldLen(fs.List)
+	for _, f := range fs.List {
+		for _, alias := range f.aliases {
+			if alias.size.ToSize() > max.ToSize() {
+				max = alias.size
+			}
+		}
+	}
+	if fieldLen.ToSize() > max.ToSize() {
+		return strictErrorf("field name size %s exceeds %s of %s", fieldLen.ToString(), max.ToString(), fs.Type)
+	}
+	return nil
+}
+
+// checkFieldWidth : FieldNameSizeFlagMixed의 필드별 크기가 필드 해시의 크기와 같은지 확인
+// f가 nil(알 수 없는 필드)이면 struct 필드 해시의 최대 크기보다 크지 않은지 확인한다.
+func (d *Decoder) checkFieldWidth(fs *fields, f *Field, fname FieldName) error {
+	if !d.strict() {
+		return nil
+	}
+	if f == nil {
+		return d.checkFieldLen(fs, fname.size)
+	}
+	if f.fieldName.hash32 == fname.hash32 && f.fieldName.size == fname.size {
+		return nil
+	}
+	for _, alias := range f.aliases {
+		if alias.hash32 == fname.hash32 && alias.size == fname.size {
+			return nil
+		}
+	}
+	return strictErrorf("field %s encoded with size %s", f.goName, fname.size.ToString())
+}
+
+// structLayout : UseStrict에서 struct 필드의 순서와 해시 크기 플래그가 Encoder와 같은지 확인
+// Encoder는 필드를 fields.List 순서로, 보존한 알 수 없는 필드(UnknownFields)를 그 뒤에 기록한다. nil이면 확인하지 않는다.
+type structLayout struct {
+	next    int               // 다음 필드가 가질 수 있는 fields.List의 최소 위치
+	unknown bool              // 알 수 없는 필드가 나왔는지
+	max     FieldNameSizeFlag // 읽은 필드 해시 크기의 최대값
+	sum     int               // 읽은 필드 해시 크기의 합 (바이트)
+}
+
+func newStructLayout(d *Decoder) *structLayout {
+	if !d.strict() {
+		return nil
+	}
+	return &structLayout{max: FieldNameSizeFlag1Byte}
+}
+
+// add : 키 fname으로 읽은 필드 f(알 수 없는 필드이면 nil)의 순서를 확인하고 해시 크기를 더함
+// 균일 크기 플래그에서 알 수 없는 필드의 크기는 알 수 없으므로 플래그의 크기로 센다.
+func (l *structLayout) add(f *Field, fname FieldName) error {
+	if l == nil {
+		return nil
+	}
+	if f == nil {
+		l.unknown = true
+	} else {
+		if l.unknown || f.pos < l.next {
+			return strictErrorf("field %s out of order", f.goName)
+		}
+		l.next = f.pos + 1
+	}
+
+	if fname.size == FieldNameSizeFlagString {
+		return nil
+	}
+	size := fname.size
+	if f != nil {
+		size = f.keySize(fname)
+	}
+	if size.ToSize() > l.max.ToSize() {
+		l.max = size
+	}
+	l.sum += size.ToSize()
+	return nil
+}
+
+// check : 필드 n개를 읽은 뒤 해시 크기 플래그 fieldLen이 encodeFieldLen이 고르는 플래그인지 확인
+func (l *structLayout) check(n int, fieldLen FieldNameSizeFlag) error {
+	if l == nil || fieldLen == FieldNameSizeFlagString {
+		return nil
+	}
+	if want := fieldLenLayout(n, l.max, l.sum); want != fieldLen {
+		return strictErrorf("field name size %s, encoder writes %s", fieldLen.ToString(), want.ToString())
+	}
+	return nil
+}
+
+// checkMixedWidths : FieldNameSizeFlagMixed 비트맵에서 필드 n개 뒤의 남는 비트가 0인지 확인
+func (d *Decoder) checkMixedWidths(widths []byte, n int) error {
+	if !d.strict() || n%4 == 0 {
+		return nil
+	}
+	if widths[len(widths)-1]&(0xff>>(2*(n%4))) != 0 {
+		return strictErrorf("non-zero padding in field size bitmap")
+	}
+	return nil
+}
+
+// keySet : UseStrict에서 이번 디코딩에 같은 map 키나 struct 필드가 두 번 나오는지 확인
+// 디코딩 전부터 map에 있던 키와 구분하기 위해 읽은 키를 따로 모은다. nil이면 확인하지 않는다.
+type keySet[K comparable] map[K]struct{}
+
+func newKeySet[K comparable](d *Decoder) keySet[K] {
+	if !d.strict() {
+		return nil
+	}
+	return make(keySet[K])
+}
+
+// add : k를 추가. 이미 나온 키이면 false
+func (s keySet[K]) add(k K) bool {
+	if s == nil {
+		return true
+	}
+	if _, ok := s[k]; ok {
+		return false
+	}
+	s[k] = struct{}{}
+	return true
+}
+
+func errDuplicateMapKey(k interface{}) error {
+	return strictErrorf("duplicate map key %v", k)
+}
@@ -0,0 +1,87 @@
+package hpack_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+func TestUnmarshalStrict(t *testing.T) {
+	canonical, err := hpack.Marshal(&vClean{ID: 1, Name: "kim"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := byte(schemaOf(t, vClean{}).Types[0].Field("id").Hash)
+	name := byte(schemaOf(t, vClean{}).Types[0].Field("name").Hash)
+
+	// mixedWidths는 Encoder가 FieldNameSizeFlagMixed로 기록하므로 모든 필드를 2B로 기록하면 거부한다.
+	uniform := []byte{hpack.FixedMapLow | 7, byte(hpack.FieldNameSizeFlag2Byte)}
+	for i, f := range schemaOf(t, mixedWidths{}).Types[0].Fields {
+		uniform = append(uniform, byte(f.Hash>>8), byte(f.Hash), byte(i))
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		v    interface{}
+	}{
+		{"trailing bytes", append(append([]byte(nil), canonical...), 0xc0), new(vClean)},
+		{"duplicate field", []byte{hpack.FixedMapLow | 2, 0, id, 1, id, 2}, new(vClean)},
+		{"duplicate map key", []byte{hpack.FixedMapLow | 2, 0xa1, 'a', 1, 0xa1, 'a', 2}, new(map[string]int)},
+		{"long uint", []byte{hpack.Uint8, 5}, new(int)},
+		{"long int", []byte{hpack.Int16, 0xff, 0x80}, new(int)},
+		{"long string length", []byte{hpack.Str8, 1, 'a'}, new(string)},
+		{"long array length", []byte{hpack.Array16, 0, 1, 1}, new([]int)},
+		{"field order", []byte{hpack.FixedMapLow | 2, 0, name, 0xa3, 'k', 'i', 'm', id, 1}, new(vClean)},
+		{"nil int", []byte{hpack.Nil}, new(int)},
+		{"nil bool", []byte{hpack.Nil}, new(bool)},
+		{"nil string", []byte{hpack.Nil}, new(string)},
+		{"nil struct", []byte{hpack.Nil}, new(vClean)},
+		{"nil array", []byte{hpack.Nil}, new([2]int)},
+		{"nil field", []byte{hpack.FixedMapLow | 1, 0, id, hpack.Nil}, new(vClean)},
+		{"int as float64", []byte{5}, new(float64)},
+		{"float as float64", []byte{hpack.Float, 0x3f, 0xc0, 0, 0}, new(float64)},
+		{"double as float32", []byte{hpack.Double, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, new(float32)},
+		{"uniform for mixed widths", uniform, new(mixedWidths)},
+		{"mixed for 1B fields", []byte{hpack.FixedMapLow | 2, byte(hpack.FieldNameSizeFlagMixed), 0, id, 1, name, 0xa3, 'k', 'i', 'm'}, new(vClean)},
+		{"size flag without fields", []byte{hpack.FixedMapLow, byte(hpack.FieldNameSizeFlag2Byte)}, new(aliasOld)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := hpack.Unmarshal(tt.b, reflect.New(reflect.TypeOf(tt.v).Elem()).Interface()); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			err := hpack.UnmarshalStrict(tt.b, tt.v)
+			var serr *hpack.StrictError
+			if !errors.Is(err, hpack.ErrNonCanonical) || !errors.As(err, &serr) || serr.Reason == "" {
+				t.Fatalf("got %v, want StrictError", err)
+			}
+		})
+	}
+
+	var out vClean
+	if err := hpack.UnmarshalStrict(canonical, &out); err != nil || out != (vClean{ID: 1, Name: "kim"}) {
+		t.Fatalf("canonical: got %+v, err %v", out, err)
+	}
+
+	// Encoder가 기록한 값은 그대로 읽는다. nil embedded 포인터를 거친 필드는 Nil로 기록된다.
+	canonicals := []interface{}{
+		&mixedWidths{F2: 1, E: 7},
+		&aliasOld{F50: 1},
+		&fuzzPacket{Items: []fuzzItem{{ID: 1}}},
+		&struct{}{},
+		1.5,
+		float32(1.5),
+	}
+	for _, in := range canonicals {
+		b, err := hpack.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := hpack.UnmarshalStrict(b, reflect.New(reflect.TypeOf(in)).Interface()); err != nil {
+			t.Fatalf("%T: %v", in, err)
+		}
+	}
+}
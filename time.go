@@ -21,6 +21,10 @@ func init() {
 }
 
 func timeEncoder(e *Encoder, v reflect.Value) ([]byte, error) {
+	// 주소를 얻을 수 있으면 포인터로 꺼내 time.Time을 interface에 담는 할당을 피한다.
+	if v.CanAddr() {
+		return e.encodeTime(*v.Addr().Interface().(*time.Time)), nil
+	}
 	return e.encodeTime(v.Interface().(time.Time)), nil
 }
 
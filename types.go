@@ -3,8 +3,11 @@ package hpack
 import (
 	"encoding"
 	"fmt"
-	"log"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/vmihailenco/tagparser/v2"
@@ -71,16 +74,26 @@ type Field struct {
 	index     []int
 	omitEmpty bool
 	fieldName FieldName
+	goName    string       // Go struct 필드명
+	typ       reflect.Type // Go 필드 타입
+	inline    string       // 인라인된 embedded struct 경로 (예: "Base.Inner")
+	aliases   []FieldName  // alias= 태그로 지정한 이전 필드명. 디코딩에만 사용
+	unknown   bool         // `,unknown` 태그로 지정한 UnknownFields 필드
+	key       [4]byte      // 인코딩할 해시의 big-endian 바이트. fields에 추가할 때 계산하며, 해시 크기 n이면 뒤쪽 n바이트를 기록한다.
+	err       error        // 태그 오류. getFields에서 IssueInvalid로 보고하고 필드에서 제외한다.
+	pos       int          // fields.List에서의 위치. UseStrict에서 필드 순서를 확인한다.
 }
 type FieldName struct {
 	name   string
 	hash32 uint32            // 최종 할당된 해시값 (1, 2, 4 바이트 중 하나)
 	size   FieldNameSizeFlag // 최종 할당된 해시 크기 Flag. 필드 중 최대값으로 사용
+	pinned bool              // 태그(hash=, id=)로 고정된 해시
 }
 
 func (f FieldName) GetName() string                { return f.name }
 func (f FieldName) GetHash32() uint32              { return f.hash32 }
 func (f FieldName) GetSizeFlag() FieldNameSizeFlag { return f.size }
+func (f FieldName) IsPinned() bool                 { return f.pinned }
 func (f FieldName) toBuffer(fieldLen FieldNameSizeFlag) (buf []byte, err error) {
 	// if fieldLen.ToSize() > f.size.ToSize() {
 	// 	fmt.Printf("field name size %d is smaller than required %d\n", f.size.ToSize(), fieldLen.ToSize())
@@ -110,6 +123,68 @@ func (f FieldName) toBuffer(fieldLen FieldNameSizeFlag) (buf []byte, err error)
 	return buf, nil
 }
 
+// pin : 태그의 hash=, id= 옵션으로 해시를 고정
+// 해시 크기는 값이 들어가는 최소 크기(1, 2, 4B)로 결정한다.
+func (f *FieldName) pin(tag *tagparser.Tag) error {
+	hashes := tagOptionValues(tag, "hash")
+	ids := tagOptionValues(tag, "id")
+	if len(hashes)+len(ids) == 0 {
+		return nil
+	}
+	if len(hashes)+len(ids) > 1 {
+		return fmt.Errorf("hpack: only one of hash= or id= is allowed for field %s", f.name)
+	}
+
+	return f.pinHash(append(hashes, ids...)[0])
+}
+
+// pinHash : 해시를 문자열 s(10진수 또는 0x로 시작하는 16진수)로 고정
+func (f *FieldName) pinHash(s string) error {
+	h, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return fmt.Errorf("hpack: invalid pinned hash %q for field %s: %w", s, f.name, err)
+	}
+
+	f.hash32 = uint32(h)
+	f.pinned = true
+	switch {
+	case h <= math.MaxUint8:
+		f.size = FieldNameSizeFlag1Byte
+	case h <= math.MaxUint16:
+		f.size = FieldNameSizeFlag2Byte
+	default:
+		f.size = FieldNameSizeFlag4Byte
+	}
+	return nil
+}
+
+// tagOptionValues : "key=value" 형식 옵션의 값 목록
+// tagparser는 ':'만 key/value로 분리하므로 '='는 옵션명에 그대로 남는다.
+func tagOptionValues(tag *tagparser.Tag, key string) []string {
+	prefix := key + "="
+
+	var values []string
+	for opt := range tag.Options {
+		if strings.HasPrefix(opt, prefix) {
+			values = append(values, strings.TrimSpace(opt[len(prefix):]))
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// aliasOptions : alias= 옵션의 [이름, 고정 해시] 목록. alias=hp:0x3a처럼 해시를 고정하지 않으면 고정 해시는 ""
+func aliasOptions(tag *tagparser.Tag) [][2]string {
+	var aliases [][2]string
+	for opt, hash := range tag.Options {
+		if name, ok := strings.CutPrefix(opt, "alias="); ok {
+			aliases = append(aliases, [2]string{strings.TrimSpace(name), strings.TrimSpace(hash)})
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i][0] < aliases[j][0] })
+	return aliases
+}
+
 func (f *Field) Omit(e *Encoder, strct reflect.Value) bool {
 	v, ok := fieldByIndex(strct, f.index)
 	if !ok {
@@ -129,7 +204,20 @@ func (f *Field) EncodeValue(e *Encoder, strct reflect.Value) error {
 }
 
 func (f *Field) DecodeValue(d *Decoder, strct reflect.Value) error {
-	v := fieldByIndexAlloc(strct, f.index)
+	// nil embedded 포인터를 거친 필드는 Nil로 기록되므로(EncodeValue) 포인터를 할당하지 않는다.
+	if len(f.index) > 1 && d.hasNilCode() {
+		if _, ok := fieldByIndex(strct, f.index); !ok {
+			return d.DecodeNil()
+		}
+	}
+
+	v, ok := fieldByIndexAlloc(strct, f.index)
+	if !ok {
+		return fmt.Errorf("hpack: cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+	}
+	if !v.CanSet() {
+		return fmt.Errorf("hpack: cannot set unexported field %s", f.goName)
+	}
 	return f.decoder(d, v)
 }
 
@@ -145,11 +233,15 @@ func newFields(typ reflect.Type) *fields {
 
 func (fs *fields) Add(field *Field) {
 	if _, ok := fs.Map[field.fieldName.hash32]; ok {
-		fmt.Printf("hpack: %s already has field=%v(%s)\n", fs.Type, field.fieldName.hash32, field.fieldName.name)
+		logf("hpack: %s already has field=%v(%s)", fs.Type, field.fieldName.hash32, field.fieldName.name)
 
 		return
 	}
 
+	h := field.fieldName.hash32
+	field.key = [4]byte{byte(h >> 24), byte(h >> 16), byte(h >> 8), byte(h)}
+
+	field.pos = len(fs.List)
 	fs.Map[field.fieldName.hash32] = field
 	fs.List = append(fs.List, field)
 	if field.omitEmpty {
@@ -157,21 +249,64 @@ func (fs *fields) Add(field *Field) {
 	}
 }
 
+// byName : 필드명(또는 alias)으로 필드를 찾음 (FieldNameSizeFlagString)
+// 필드명을 각 크기로 해싱하여 Map에서 찾고, 해시를 고정한 필드는 List에서 찾는다.
+func (fs *fields) byName(name string) *Field {
+	for _, sizeFlag := range fieldNameSizeFlagValues {
+		if f := fs.Map[getHashcode(fs.Hasher, name, sizeFlag)]; f != nil && f.hasName(name) {
+			return f
+		}
+	}
+	for _, f := range fs.List {
+		if f.hasName(name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// keySize : 해시 키 fname으로 읽은 필드의 해시 크기. alias의 해시이면 alias의 크기
+func (f *Field) keySize(fname FieldName) FieldNameSizeFlag {
+	if fname.hash32 != f.fieldName.hash32 {
+		for _, alias := range f.aliases {
+			if alias.hash32 == fname.hash32 {
+				return alias.size
+			}
+		}
+	}
+	return f.fieldName.size
+}
+
+// hasName : 필드명 또는 alias가 name인지 여부
+func (f *Field) hasName(name string) bool {
+	if f.fieldName.name == name {
+		return true
+	}
+	for _, alias := range f.aliases {
+		if alias.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// OmitEmpty : 인코딩할 필드. 걸러낸 필드는 e.omitFields 뒤에 쌓으므로,
+// 사용이 끝나면 호출 전 길이로 e.omitFields를 되돌린다.
 func (fs *fields) OmitEmpty(e *Encoder, strct reflect.Value) []*Field {
 	forced := e.flags&omitEmptyFlag != 0
 	if !fs.hasOmitEmpty && !forced {
 		return fs.List
 	}
 
-	fields := make([]*Field, 0, len(fs.List))
-
+	start := len(e.omitFields)
 	for _, f := range fs.List {
 		if !f.Omit(e, strct) {
-			fields = append(fields, f)
+			e.omitFields = append(e.omitFields, f)
 		}
 	}
 
-	return fields
+	end := len(e.omitFields)
+	return e.omitFields[start:end:end]
 }
 
 func fold32to8(h uint32) uint32 {
@@ -184,127 +319,375 @@ func fold32to16(h uint32) uint32 {
 	return uint32(v) // 0x0000XXXX
 }
 
-func (fs *fields) getHashcode(nameStr string, rqSize FieldNameSizeFlag) uint32 {
-	h32 := CRC32Hash(nameStr) // CRC-32 사용
-
-	var hashcode uint32 = 0
+// getHashcode : 필드명을 요청한 크기(1, 2, 4B)의 해시코드로 변환
+// 중복 여부는 판단하지 않는다(assignHashcodes 참고).
+func getHashcode(hasher FieldHasher, nameStr string, rqSize FieldNameSizeFlag) uint32 {
+	h32 := hasher.Hash32(nameStr)
 
 	switch rqSize {
 	case FieldNameSizeFlag1Byte:
-		hashcode = fold32to8(h32)
+		return fold32to8(h32)
 	case FieldNameSizeFlag2Byte:
-		hashcode = fold32to16(h32)
+		return fold32to16(h32)
 	case FieldNameSizeFlag4Byte:
-		hashcode = h32
-	default:
-		fmt.Printf("getHashcode invalid size flag:%d, %s \n", rqSize, nameStr)
-		return hashcode
+		return h32
 	}
 
-	if _, ok := fs.Map[hashcode]; !ok { // unique
-		return hashcode
+	logf("hpack: getHashcode invalid size flag:%d, %s", rqSize, nameStr)
+	return 0
+}
+
+// assignHashcodes : 필드명 집합 전체를 기준으로 해시코드를 할당
+//
+// 1B 해시가 집합 내에서 유일한 필드는 1B를 할당하고, 나머지는 2B -> 4B 순서로 재시도한다.
+// 같은 크기에서 충돌한 필드는 모두 다음 크기로 넘어가므로 할당 결과는 필드 선언 순서와 무관하다.
+// 4B에서도 충돌한 필드는 해시를 할당하지 않고 반환한다.
+//
+// 태그로 고정된(pinned) 해시는 먼저 예약되며, 앞의 필드와 고정 해시가 겹치는 필드는 duplicated(필드 -> 앞의 필드)로 반환한다.
+func assignHashcodes(hasher FieldHasher, names []*FieldName) (collided []*FieldName, duplicated map[*FieldName]*FieldName) {
+	taken := make(map[uint32]*FieldName, len(names))
+
+	pending := make([]*FieldName, 0, len(names))
+	for _, fname := range names {
+		if !fname.pinned {
+			pending = append(pending, fname)
+			continue
+		}
+		if other, ok := taken[fname.hash32]; ok {
+			if duplicated == nil {
+				duplicated = make(map[*FieldName]*FieldName)
+			}
+			duplicated[fname] = other
+			continue
+		}
+		taken[fname.hash32] = fname
 	}
 
-	return 0
+	for _, sizeFlag := range fieldNameSizeFlagValues {
+		if len(pending) == 0 {
+			break
+		}
+
+		counts := make(map[uint32]int, len(pending))
+		for _, fname := range pending {
+			counts[getHashcode(hasher, fname.name, sizeFlag)]++
+		}
+
+		var next []*FieldName
+		for _, fname := range pending {
+			hcode := getHashcode(hasher, fname.name, sizeFlag)
+			if _, ok := taken[hcode]; ok || counts[hcode] > 1 { // 중복이면 다음 크기로 재시도
+				next = append(next, fname)
+				continue
+			}
+
+			fname.hash32 = hcode
+			fname.size = sizeFlag
+			taken[hcode] = fname
+		}
+		pending = next
+	}
+
+	return pending, duplicated
 }
 
-func getFields(typ reflect.Type) *fields {
+func getFields(typ reflect.Type, hasher FieldHasher, tags structTags) *fields {
 	fs := newFields(typ)
+	fs.Hasher = hasher
+
+	if tag := structOptionsTag(typ, tags); tag != nil {
+		fs.AsArray = tag.HasOption("as_array") || tag.HasOption("asArray")
+	}
+
+	list, embedded, shadowed := collectFields(typ, tags)
+
+	// 태그 오류가 있는 필드는 제외하고 보고 (structs.Fields는 에러를 반환)
+	// `,unknown` 필드는 해시를 할당하지 않고 따로 보관
+	known := make([]*Field, 0, len(list))
+	for _, field := range list {
+		switch {
+		case field.err != nil:
+			fs.invalid(field.goName, field.err)
+		case !field.unknown:
+			known = append(known, field)
+		case fs.unknown != nil:
+			fs.invalid(field.goName, fmt.Errorf("hpack: %s has more than one unknown field (%s, %s)", typ, fs.unknown.goName, field.goName))
+		default:
+			fs.unknown = field
+		}
+	}
+	list = known
+
+	valid := make([]*Field, 0, len(embedded))
+	for _, field := range embedded {
+		if field.err != nil {
+			fs.invalid(field.goName, field.err)
+			continue
+		}
+		valid = append(valid, field)
+	}
+	embedded = valid
+
+	names := make([]*FieldName, 0, len(list)+len(embedded))
+	for _, field := range list {
+		names = append(names, &field.fieldName)
+	}
+	for _, field := range embedded {
+		names = append(names, &field.fieldName)
+	}
+
+	collidedNames, duplicated := assignHashcodes(hasher, names)
+
+	collided := make(map[*FieldName]struct{})
+	for _, fname := range names {
+		if other, ok := duplicated[fname]; ok {
+			fs.invalid(fname.name, fmt.Errorf("hpack: fields %s and %s have the same pinned hash=%#x (struct %s)",
+				other.name, fname.name, fname.hash32, typ))
+			collided[fname] = struct{}{}
+		}
+	}
+	for _, fname := range collidedNames {
+		logf("hpack: getFields hash collision for field %s in %s", fname.name, typ)
+		collided[fname] = struct{}{}
+		fs.issues = append(fs.issues, SchemaIssue{Kind: IssueHashCollision, Type: typ, Field: fname.name})
+	}
+
+	for _, field := range list {
+		if _, ok := collided[&field.fieldName]; ok {
+			continue
+		}
+		if !field.fieldName.pinned && field.fieldName.size != FieldNameSizeFlag1Byte {
+			fs.issues = append(fs.issues, SchemaIssue{
+				Kind:  IssueWidened,
+				Type:  typ,
+				Field: field.fieldName.name,
+				Size:  field.fieldName.size,
+			})
+		}
+		fs.Add(field)
+	}
+
+	for _, field := range shadowed {
+		fs.issues = append(fs.issues, SchemaIssue{Kind: IssueShadowed, Type: typ, Field: field.fieldName.name})
+	}
+
+	// 인라인된 embedded struct 자체는 decode 전용
+	for _, field := range embedded {
+		if _, ok := collided[&field.fieldName]; ok {
+			continue
+		}
+		if _, ok := fs.Map[field.fieldName.hash32]; ok {
+			logf("hpack: %s already has field=%s", fs.Type, field.fieldName.name)
+		}
+		fs.Map[field.fieldName.hash32] = field
+	}
+
+	fs.addAliases(hasher, names)
+
+	return fs
+}
+
+// addAliases : alias(decode 전용)의 해시를 Map에 추가
+//
+// 기본 필드의 해시가 alias 때문에 바뀌지 않도록 기본 필드를 모두 할당한 뒤에 추가한다.
+// 이전 struct에서 alias 이름이 어떤 크기로 기록되었는지 알 수 없으므로 1B, 2B, 4B 해시를 모두 매핑한다. (alias=hp:0x3a로 고정하면 그 해시만)
+// 다른 필드명과 같은 alias, 기본 필드나 다른 alias의 해시와 겹치는 해시는 매핑하지 않고 IssueAliasConflict로 보고한다.
+func (fs *fields) addAliases(hasher FieldHasher, primary []*FieldName) {
+	names := make(map[string]struct{}, len(primary))
+	for _, fname := range primary {
+		names[fname.name] = struct{}{}
+	}
+
+	candidates := make(map[*Field][]FieldName)
+	counts := make(map[uint32]int)
+	for _, field := range fs.List {
+		for _, alias := range field.aliases {
+			if _, ok := names[alias.name]; ok {
+				logf("hpack: alias %s of %s.%s is another field's name", alias.name, fs.Type, field.goName)
+				fs.issues = append(fs.issues, SchemaIssue{Kind: IssueAliasConflict, Type: fs.Type, Field: alias.name})
+				continue
+			}
+			if alias.pinned {
+				candidates[field] = append(candidates[field], alias)
+				counts[alias.hash32]++
+				continue
+			}
+			seen := make(map[uint32]struct{}, len(fieldNameSizeFlagValues))
+			for _, sizeFlag := range fieldNameSizeFlagValues {
+				hcode := getHashcode(hasher, alias.name, sizeFlag)
+				if _, ok := seen[hcode]; ok { // 접은 해시가 작은 크기와 같은 경우
+					continue
+				}
+				seen[hcode] = struct{}{}
+				candidates[field] = append(candidates[field], FieldName{name: alias.name, hash32: hcode, size: sizeFlag})
+				counts[hcode]++
+			}
+		}
+	}
+
+	for _, field := range fs.List {
+		field.aliases = field.aliases[:0]
+		for _, alias := range candidates[field] {
+			if _, ok := fs.Map[alias.hash32]; ok || counts[alias.hash32] > 1 {
+				logf("hpack: alias %s (%s) of %s.%s conflicts with another field", alias.name, alias.size.ToString(), fs.Type, field.goName)
+				fs.issues = append(fs.issues, SchemaIssue{Kind: IssueAliasConflict, Type: fs.Type, Field: alias.name, Size: alias.size})
+				continue
+			}
+			field.aliases = append(field.aliases, alias)
+		}
+		for _, alias := range field.aliases {
+			fs.Map[alias.hash32] = field
+		}
+	}
+}
+
+// structOptionsTag : struct 전체 옵션(omitempty, as_array)을 지정하는 `_hpack`(또는 `_msgpack`) 필드의 태그. 없으면 nil
+// `_hpack`은 hpack 태그를, 없으면 필드와 같은 태그(tags)를 읽는다.
+//
+//	type Position struct {
+//		_hpack struct{} `hpack:",as_array"`
+//		X, Y   float32
+//	}
+func structOptionsTag(typ reflect.Type, tags structTags) *tagparser.Tag {
+	if f, ok := typ.FieldByName("_hpack"); ok && len(f.Index) == 1 {
+		tagStr, ok := f.Tag.Lookup("hpack")
+		if !ok {
+			tagStr = tags.get(f)
+		}
+		return tagparser.Parse(tagStr)
+	}
+	if f, ok := typ.FieldByName("_msgpack"); ok && len(f.Index) == 1 {
+		return tagparser.Parse(tags.get(f))
+	}
+	return nil
+}
+
+// collectFields : 해시코드 할당 전의 필드 목록을 선언 순서대로 수집
+//
+// 인라인된 embedded struct의 필드는 list에 펼치고, embedded 필드 자체는 embedded로 반환한다.
+// embedded struct의 필드는 같은 이름의 필드가 이미 있으면 가려지며(shadowed) 인라인되지 않는다.
+func collectFields(typ reflect.Type, tags structTags) (list, embedded, shadowed []*Field) {
+	type entry struct {
+		field  *Field
+		typ    reflect.Type
+		tag    *tagparser.Tag
+		anonym bool
+	}
+
 	var omitEmpty bool
+	if tag := structOptionsTag(typ, tags); tag != nil {
+		omitEmpty = tag.HasOption("omitempty")
+	}
+
+	entries := make([]entry, 0, typ.NumField())
+	names := make(map[string]struct{}, typ.NumField())
 	for i := 0; i < typ.NumField(); i++ {
 		f := typ.Field(i)
 
-		tagStr := f.Tag.Get(defaultStructTag)
-		// if tagStr == "" && fallbackTag != "" {
-		// 	tagStr = f.Tag.Get(fallbackTag)
-		// }
-
-		tag := tagparser.Parse(tagStr)
+		tag := tagparser.Parse(tags.get(f))
 		if tag.Name == "-" {
 			continue
 		}
 
-		// if f.Name == "_msgpack" {
-		// 	fs.AsArray = tag.HasOption("as_array") || tag.HasOption("asArray")
-		// 	if tag.HasOption("omitempty") {
-		// 		omitEmpty = true
-		// 	}
-		// }
-
 		if f.PkgPath != "" && !f.Anonymous {
 			continue
 		}
 
+		if tag.HasOption("unknown") {
+			field := &Field{index: f.Index, goName: f.Name, typ: f.Type, unknown: true}
+			if f.Type != unknownFieldsType {
+				field.err = fmt.Errorf("hpack: unknown tag option requires hpack.UnknownFields, but %s.%s is %s", typ, f.Name, f.Type)
+			}
+			entries = append(entries, entry{field: field, typ: f.Type, tag: tag})
+			continue
+		}
+
 		field := &Field{
 			fieldName: FieldName{
 				name: tag.Name,
 			},
 			index:     f.Index,
 			omitEmpty: omitEmpty || tag.HasOption("omitempty"),
+			goName:    f.Name,
+			typ:       f.Type,
 		}
 		if field.fieldName.name == "" {
 			field.fieldName.name = f.Name
 		}
+		if err := field.fieldName.pin(tag); err != nil {
+			field.err = fmt.Errorf("%w (field %s.%s)", err, typ, f.Name)
+		}
 
-		for _, sizeFlag := range fieldNameSizeFlagValues {
-			hcode := fs.getHashcode(field.fieldName.name, sizeFlag)
-			if hcode == 0 { // 중복이면 다시 getHashcode
-				fmt.Printf("getFields hash collision for field %s with size %d, try next size\n", field.fieldName.name, sizeFlag)
-				continue
+		if tag.HasOption("intern") {
+			switch {
+			case f.Type.Kind() == reflect.Interface:
+				field.encoder = encodeInternedInterfaceValue
+				field.decoder = decodeInternedInterfaceValue
+			case f.Type.Kind() == reflect.String:
+				field.encoder = encodeInternedStringValue
+				field.decoder = decodeInternedStringValue
+			case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.String:
+				field.encoder = encodeInternedStringSliceValue
+				field.decoder = decodeInternedStringSliceValue
+			case f.Type.Kind() == reflect.Map && f.Type.Key().Kind() == reflect.String:
+				field.encoder = encodeInternedMapValue
+				field.decoder = decodeInternedMapValue
+			default:
+				field.err = fmt.Errorf("hpack: intern strings are not supported on %s (field %s.%s)", f.Type, typ, f.Name)
 			}
+		} else {
+			field.encoder = getEncoder(f.Type)
+			field.decoder = getDecoder(f.Type)
+		}
 
-			field.fieldName.hash32 = hcode
-			field.fieldName.size = sizeFlag
-			break
+		anonym := f.Anonymous && !tag.HasOption("noinline")
+		if !anonym {
+			names[field.fieldName.name] = struct{}{}
 		}
+		entries = append(entries, entry{field: field, typ: f.Type, tag: tag, anonym: anonym})
 
-		field.encoder = getEncoder(f.Type)
-		field.decoder = getDecoder(f.Type)
-		/*
-			if tag.HasOption("intern") {
-				switch f.Type.Kind() {
-				case reflect.Interface:
-					field.encoder = encodeInternedInterfaceValue
-					field.decoder = decodeInternedInterfaceValue
-				case reflect.String:
-					field.encoder = encodeInternedStringValue
-					field.decoder = decodeInternedStringValue
-				default:
-					err := fmt.Errorf("hpack: intern strings are not supported on %s", f.Type)
-					panic(err)
+		for _, alias := range aliasOptions(tag) {
+			fname := FieldName{name: alias[0]}
+			if alias[1] != "" {
+				if err := fname.pinHash(alias[1]); err != nil {
+					field.err = fmt.Errorf("%w (field %s.%s)", err, typ, f.Name)
 				}
-			} else {
-				field.encoder = getEncoder(f.Type)
-				field.decoder = getDecoder(f.Type)
 			}
-		*/
+			field.aliases = append(field.aliases, fname)
+		}
+	}
 
+	for _, ent := range entries {
 		// Embeded struct를 인라인 처리
-		if f.Anonymous && !tag.HasOption("noinline") {
-			inline := tag.HasOption("inline")
-			if inline {
-				inlineFields(fs, f.Type, field)
+		if ent.anonym {
+			var inline bool
+			if ent.tag.HasOption("inline") {
+				var dropped []*Field
+				list, dropped = inlineFields(list, names, ent.typ, ent.field, tags)
+				shadowed = append(shadowed, dropped...)
+				inline = true
 			} else {
-				inline = shouldInline(fs, f.Type, field)
+				list, inline = shouldInline(list, names, ent.typ, ent.field, tags)
 			}
 
 			if inline {
-				if _, ok := fs.Map[field.fieldName.hash32]; ok {
-					log.Printf("hpack: %s already has field=%s", fs.Type, field.fieldName.name)
-				}
-				fs.Map[field.fieldName.hash32] = field
+				embedded = append(embedded, ent.field)
 				continue
 			}
+			names[ent.field.fieldName.name] = struct{}{}
 		}
 
-		fs.Add(field)
+		list = append(list, ent.field)
+	}
 
-		// if alias, ok := tag.Options["alias"]; ok {
-		// 	fs.warnIfFieldExists(alias)
-		// 	fs.Map[alias] = field
-		// }
+	// struct 단위 omitempty는 인라인된 필드에도 적용
+	if omitEmpty {
+		for _, field := range list {
+			field.omitEmpty = true
+		}
 	}
-	return fs
+
+	return list, embedded, shadowed
 }
 
 func fieldByIndex(v reflect.Value, index []int) (_ reflect.Value, ok bool) {
@@ -328,23 +711,24 @@ func fieldByIndex(v reflect.Value, index []int) (_ reflect.Value, ok bool) {
 	return v, true
 }
 
-func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+// fieldByIndexAlloc : index 경로의 필드. 경로의 nil 포인터는 할당한다.
+// 할당할 수 없으면(unexported embedded struct 포인터) 할당하지 못한 포인터와 false를 반환
+func fieldByIndexAlloc(v reflect.Value, index []int) (_ reflect.Value, ok bool) {
 	if len(index) == 1 {
-		return v.Field(index[0])
+		return v.Field(index[0]), true
 	}
 
 	for i, idx := range index {
 		if i > 0 {
-			var ok bool
 			v, ok = indirectNil(v)
 			if !ok {
-				return v
+				return v, false
 			}
 		}
 		v = v.Field(idx)
 	}
 
-	return v
+	return v, true
 }
 
 func indirectNil(v reflect.Value) (reflect.Value, bool) {
@@ -368,6 +752,8 @@ type isZeroer interface {
 	IsZero() bool
 }
 
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
 func (e *Encoder) isEmptyValue(v reflect.Value) bool {
 	kind := v.Kind()
 
@@ -379,18 +765,24 @@ func (e *Encoder) isEmptyValue(v reflect.Value) bool {
 		kind = v.Kind()
 	}
 
-	if z, ok := v.Interface().(isZeroer); ok {
-		return nilable(kind) && v.IsNil() || z.IsZero()
+	// Interface()는 값을 복사하므로 IsZero를 구현한 타입에만 호출
+	if v.Type().Implements(isZeroerType) {
+		return nilable(kind) && v.IsNil() || v.Interface().(isZeroer).IsZero()
 	}
 
 	switch kind {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
 	case reflect.Struct:
-		// structFields := structs.Fields(v.Type(), e.structTag)
-		structFields := structs.Fields(v.Type())
-		fields := structFields.OmitEmpty(e, v)
-		return len(fields) == 0
+		structFields, err := structs.Fields(v.Type(), e.hasher, e.tags)
+		if err != nil {
+			// 비어 있지 않은 것으로 보고 인코딩할 때 태그 오류를 반환한다.
+			return false
+		}
+		n := len(e.omitFields)
+		empty := len(structFields.OmitEmpty(e, v)) == 0
+		e.releaseFields(n)
+		return empty
 	case reflect.Bool:
 		return !v.Bool()
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -407,29 +799,39 @@ func (e *Encoder) isEmptyValue(v reflect.Value) bool {
 }
 
 var (
-	encodeStructValuePtr uintptr
-	decodeStructValuePtr uintptr
+	encodeStructValuePtr      uintptr
+	decodeStructValuePtr      uintptr
+	encodeUnsupportedValuePtr uintptr
 )
 
 //nolint:gochecknoinits
 func init() {
 	encodeStructValuePtr = reflect.ValueOf(encodeStructValue).Pointer()
 	decodeStructValuePtr = reflect.ValueOf(decodeStructValue).Pointer()
+	encodeUnsupportedValuePtr = reflect.ValueOf(encodeUnsupportedValue).Pointer()
 }
 
-func inlineFields(fs *fields, typ reflect.Type, f *Field) {
-	inlinedFields := getFields(typ).List
+func inlineFields(list []*Field, names map[string]struct{}, typ reflect.Type, f *Field, tags structTags) (_ []*Field, shadowed []*Field) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	inlinedFields, _, _ := collectFields(typ, tags)
 	for _, field := range inlinedFields {
-		if _, ok := fs.Map[field.fieldName.hash32]; ok {
+		if _, ok := names[field.fieldName.name]; ok {
 			// Don't inline shadowed fields.
+			shadowed = append(shadowed, field)
 			continue
 		}
-		field.index = append(f.index, field.index...)
-		fs.Add(field)
+		names[field.fieldName.name] = struct{}{}
+		field.index = joinIndex(f.index, field.index)
+		field.inline = joinInline(f.goName, field.inline)
+		list = append(list, field)
 	}
+	return list, shadowed
 }
 
-func shouldInline(fs *fields, typ reflect.Type, f *Field) bool {
+func shouldInline(list []*Field, names map[string]struct{}, typ reflect.Type, f *Field, tags structTags) ([]*Field, bool) {
 	var encoder encoderFunc
 	var decoder decoderFunc
 
@@ -443,28 +845,43 @@ func shouldInline(fs *fields, typ reflect.Type, f *Field) bool {
 			decoder = getDecoder(typ)
 		}
 		if typ.Kind() != reflect.Struct {
-			return false
+			return list, false
 		}
 	}
 
 	if reflect.ValueOf(encoder).Pointer() != encodeStructValuePtr {
-		return false
+		return list, false
 	}
 	if reflect.ValueOf(decoder).Pointer() != decodeStructValuePtr {
-		return false
+		return list, false
 	}
 
-	inlinedFields := getFields(typ).List
+	inlinedFields, _, _ := collectFields(typ, tags)
 	for _, field := range inlinedFields {
-		if _, ok := fs.Map[field.fieldName.hash32]; ok {
+		if _, ok := names[field.fieldName.name]; ok {
 			// Don't auto inline if there are shadowed fields.
-			return false
+			return list, false
 		}
 	}
 
 	for _, field := range inlinedFields {
-		field.index = append(f.index, field.index...)
-		fs.Add(field)
+		names[field.fieldName.name] = struct{}{}
+		field.index = joinIndex(f.index, field.index)
+		field.inline = joinInline(f.goName, field.inline)
+		list = append(list, field)
 	}
-	return true
+	return list, true
+}
+
+func joinInline(parent, inline string) string {
+	if inline == "" {
+		return parent
+	}
+	return parent + "." + inline
+}
+
+func joinIndex(parent, index []int) []int {
+	joined := make([]int, 0, len(parent)+len(index))
+	joined = append(joined, parent...)
+	return append(joined, index...)
 }
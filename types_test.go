@@ -0,0 +1,300 @@
+package hpack_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+func fieldSchema(t *testing.T, v interface{}, name string) *hpack.FieldSchema {
+	t.Helper()
+	s, err := hpack.SchemaOf(reflect.TypeOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := s.Types[0].Field(name)
+	if f == nil {
+		t.Fatalf("%T has no field %s", v, name)
+	}
+	return f
+}
+
+func validationIssues(t *testing.T, v interface{}) []hpack.SchemaIssue {
+	t.Helper()
+	err := hpack.Validate(reflect.TypeOf(v))
+	if err == nil {
+		return nil
+	}
+	var verr *hpack.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want *ValidationError", err)
+	}
+	return verr.Issues
+}
+
+// structKeys : 인코딩된 struct b의 해시 크기 플래그와 필드 해시별 값 (값은 양의 fixint)
+func structKeys(t *testing.T, b []byte) (hpack.FieldNameSizeFlag, map[uint32]int) {
+	t.Helper()
+	if len(b) < 2 || b[0]&0xf0 != hpack.FixedMapLow {
+		t.Fatalf("not a struct: % x", b)
+	}
+	n, fieldLen := int(b[0]&0x0f), hpack.FieldNameSizeFlag(b[1])
+	p := b[2:]
+
+	// FieldNameSizeFlagMixed : 필드마다 2비트 크기 플래그
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = fieldLen.ToSize()
+		if fieldLen == hpack.FieldNameSizeFlagMixed {
+			sizes[i] = hpack.FieldNameSizeFlag(p[i/4] >> (6 - 2*(i%4)) << 6).ToSize()
+		}
+	}
+	if fieldLen == hpack.FieldNameSizeFlagMixed {
+		p = p[(n+3)/4:]
+	}
+
+	keys := make(map[uint32]int, n)
+	for _, size := range sizes {
+		if size <= 0 || len(p) < size+1 {
+			t.Fatalf("unexpected layout: % x", b)
+		}
+		var h uint32
+		for _, c := range p[:size] {
+			h = h<<8 | uint32(c)
+		}
+		keys[h] = int(p[size])
+		p = p[size+1:]
+	}
+	if len(p) != 0 {
+		t.Fatalf("unexpected layout: % x", b)
+	}
+	return fieldLen, keys
+}
+
+type hashOrderA struct {
+	F2  int `msgpack:"f2"`
+	F50 int `msgpack:"f50"`
+	ID  int `msgpack:"id"`
+}
+
+type hashOrderB struct {
+	ID  int `msgpack:"id"`
+	F50 int `msgpack:"f50"`
+	F2  int `msgpack:"f2"`
+}
+
+func TestHashAssignmentOrderIndependent(t *testing.T) {
+	a, err := hpack.Marshal(&hashOrderA{F2: 1, F50: 2, ID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hpack.Marshal(&hashOrderB{ID: 3, F50: 2, F2: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 선언 순서가 달라도 필드마다 같은 해시를 할당한다.
+	lenA, keysA := structKeys(t, a)
+	lenB, keysB := structKeys(t, b)
+	if lenA != lenB || len(keysA) != 3 || len(keysB) != 3 {
+		t.Fatalf("a = % x, b = % x", a, b)
+	}
+	for h, v := range keysA {
+		if keysB[h] != v {
+			t.Fatalf("key %#x: %d != %d", h, v, keysB[h])
+		}
+	}
+
+	// 1B에서 충돌한 f2, f50만 2B로 늘어난다.
+	if lenA != hpack.FieldNameSizeFlag2Byte {
+		t.Fatalf("size = %s, want 2B", lenA.ToString())
+	}
+	for h, v := range keysA {
+		if wide := h > 0xff; wide != (v != 3) {
+			t.Fatalf("key %#x for value %d", h, v)
+		}
+	}
+
+	// 선언 순서가 달라도 서로 디코딩된다.
+	var out hashOrderB
+	if err := hpack.Unmarshal(a, &out); err != nil || out != (hashOrderB{ID: 3, F50: 2, F2: 1}) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+}
+
+type pinnedFields struct {
+	HP    int `msgpack:"hp,hash=0x3a"`
+	Level int `msgpack:"level,id=7"`
+	Exp   int `msgpack:"exp,hash=0x1234"`
+	Gold  int `msgpack:"gold,hash=0x12345"`
+	F2    int `msgpack:"f2"`
+}
+
+// f2의 1B 해시(0xc2)를 다른 필드가 고정하면 f2는 2B로 늘어난다.
+type pinnedReserved struct {
+	F2 int `msgpack:"f2"`
+	X  int `msgpack:"x,hash=0xc2"`
+}
+
+func TestPinnedHash(t *testing.T) {
+	in := pinnedFields{HP: 1, Level: 2, Exp: 3, Gold: 4, F2: 5}
+	b, err := hpack.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out pinnedFields
+	if err := hpack.Unmarshal(b, &out); err != nil || out != in {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+
+	// 고정 해시는 키로 그대로 기록된다.
+	_, keys := structKeys(t, b)
+	for hash, want := range map[uint32]int{0x3a: 1, 7: 2, 0x1234: 3, 0x12345: 4} {
+		if keys[hash] != want {
+			t.Fatalf("key %#x = %d, want %d (%v)", hash, keys[hash], want, keys)
+		}
+	}
+
+	b, err = hpack.Marshal(&pinnedReserved{F2: 1, X: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fieldLen, keys := structKeys(t, b)
+	if fieldLen != hpack.FieldNameSizeFlag2Byte || keys[0xc2] != 2 {
+		t.Fatalf("got % x, want f2 widened", b)
+	}
+}
+
+type taggedCodec struct {
+	ID    int    `hpack:"id" json:"identifier"`
+	Name  string `json:"name"`
+	Level int    `msgpack:"lv"`
+	Skip  int    `hpack:"-" json:"skip"`
+}
+
+func TestStructTags(t *testing.T) {
+	in := taggedCodec{ID: 1, Name: "kim", Level: 2, Skip: 3}
+	tags := func(enc *hpack.Encoder) { enc.SetStructTags("hpack", "json") }
+	b := encode(t, &in, tags)
+
+	var out taggedCodec
+	if err := decode(b, &out, func(dec *hpack.Decoder) { dec.SetStructTags("hpack", "json") }); err != nil {
+		t.Fatal(err)
+	}
+	// hpack 태그가 없으면 json 태그, 둘 다 없으면 Go 필드명을 읽는다.
+	if out != (taggedCodec{ID: 1, Name: "kim", Level: 2}) {
+		t.Fatalf("got %+v", out)
+	}
+
+	v := decodeAny(t, &in, func(enc *hpack.Encoder) {
+		enc.SetStructTags("hpack", "json")
+		enc.UseFieldNames(true)
+	})
+	if m := v.(map[string]interface{}); len(m) != 3 || m["id"] == nil || m["name"] == nil || m["Level"] == nil {
+		t.Fatalf("keys = %v", m)
+	}
+
+	// 다른 태그로 디코딩하면 필드가 맞지 않는다.
+	out = taggedCodec{}
+	if err := hpack.Unmarshal(b, &out); err != nil || out.ID != 0 || out.Name != "" {
+		t.Fatalf("default tags: got %+v, err %v", out, err)
+	}
+
+	// SetCustomStructTag는 fallback만 지정한다.
+	out = taggedCodec{}
+	b = encode(t, &in, func(enc *hpack.Encoder) { enc.SetCustomStructTag("json") })
+	if err := decode(b, &out, func(dec *hpack.Decoder) { dec.SetCustomStructTag("json") }); err != nil || out != in {
+		t.Fatalf("SetCustomStructTag: got %+v, err %v", out, err)
+	}
+}
+
+// f2와 f50은 1B 해시(0xc2)가 같다.
+type aliasBase struct {
+	F2 int `msgpack:"f2"`
+	CC int `msgpack:"cc"`
+}
+
+type aliasWiden struct {
+	F2 int `msgpack:"f2"`
+	CC int `msgpack:"cc,alias=f50"`
+}
+
+type aliasOld struct {
+	F50  int `msgpack:"f50"`
+	F379 int `msgpack:"f379"` // f50과 1B 해시가 같아 둘 다 2B
+	Old  int `msgpack:"old,hash=0x1234"`
+}
+
+type aliasNew struct {
+	A int `msgpack:"a,alias=f50"`
+	B int `msgpack:"b,alias=f379"`
+	C int `msgpack:"c,alias=old:0x1234"`
+}
+
+type aliasSameName struct {
+	A int `msgpack:"a"`
+	B int `msgpack:"b,alias=a"`
+}
+
+func TestAliasDoesNotWidenFields(t *testing.T) {
+	base := fieldSchema(t, aliasBase{}, "f2")
+	got := fieldSchema(t, aliasWiden{}, "f2")
+	if got.Hash != base.Hash || got.Size != base.Size {
+		t.Fatalf("f2 = %#x (%s), want %#x (%s)", got.Hash, got.Size.ToString(), base.Hash, base.Size.ToString())
+	}
+
+	issues := validationIssues(t, aliasWiden{})
+	if len(issues) != 1 || issues[0].Kind != hpack.IssueAliasConflict || issues[0].Field != "f50" ||
+		issues[0].Size != hpack.FieldNameSizeFlag1Byte {
+		t.Fatalf("issues = %v, want 1B alias conflict for f50", issues)
+	}
+
+	// 1B 해시가 겹치는 f50은 f2로 디코딩된다.
+	b, err := hpack.Marshal(&aliasBase{F2: 1, CC: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out aliasWiden
+	if err := hpack.Unmarshal(b, &out); err != nil || out != (aliasWiden{F2: 1, CC: 2}) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+}
+
+func TestAliasDecodesOldWidths(t *testing.T) {
+	if f := fieldSchema(t, aliasOld{}, "f50"); f.Size != hpack.FieldNameSizeFlag2Byte {
+		t.Fatalf("f50 size = %s, want 2B", f.Size.ToString())
+	}
+
+	b, err := hpack.Marshal(&aliasOld{F50: 1, F379: 2, Old: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out aliasNew
+	if err := hpack.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != (aliasNew{A: 1, B: 2, C: 3}) {
+		t.Fatalf("got %+v", out)
+	}
+	if err := hpack.UnmarshalStrict(b, &out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAliasSameAsFieldName(t *testing.T) {
+	issues := validationIssues(t, aliasSameName{})
+	if len(issues) != 1 || issues[0].Kind != hpack.IssueAliasConflict || issues[0].Field != "a" {
+		t.Fatalf("issues = %v, want alias conflict for a", issues)
+	}
+
+	b, err := hpack.Marshal(&aliasSameName{A: 1, B: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out aliasSameName
+	if err := hpack.Unmarshal(b, &out); err != nil || out != (aliasSameName{A: 1, B: 2}) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+}
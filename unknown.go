@@ -0,0 +1,98 @@
+package hpack
+
+import "reflect"
+
+var unknownFieldsType = reflect.TypeOf(UnknownFields(nil))
+
+// UnknownField : 디코딩 대상 struct에 없어 보존한 필드
+type UnknownField struct {
+	Hash  uint32            // 필드 해시. 문자열 키이면 필드명의 4B 해시
+	Width FieldNameSizeFlag // 해시 크기(1, 2, 4B). 문자열 키이면 FieldNameSizeFlagString
+	Name  string            // 문자열 키의 필드명
+	Value RawMessage        // 인코딩된 필드 값
+}
+
+// UnknownFields : `msgpack:",unknown"` 태그를 붙인 필드에 디코딩 대상 struct에 없는 필드를 모으고, 인코딩 시 다시 기록
+//
+// 해시 키로 받은 필드는 해시 키로, 문자열 키(UseFieldNames)로 받은 필드는 문자열 키로 인코딩할 때만 기록된다.
+// 배열로 인코딩된 struct의 남는 값은 모으지 않는다.
+//
+//	type Player struct {
+//		Name    string              `msgpack:"name"`
+//		Unknown hpack.UnknownFields `msgpack:",unknown"`
+//	}
+type UnknownFields []UnknownField
+
+// resetUnknownFields : 디코딩 시작 시 이전 디코딩에서 모은 필드를 비움
+func (fs *fields) resetUnknownFields(strct reflect.Value) {
+	v, ok := fieldByIndexAlloc(strct, fs.unknown.index)
+	if ok && v.Len() > 0 {
+		v.SetLen(0)
+	}
+}
+
+func (fs *fields) addUnknownField(strct reflect.Value, uf UnknownField) {
+	v, ok := fieldByIndexAlloc(strct, fs.unknown.index)
+	if ok {
+		v.Set(reflect.Append(v, reflect.ValueOf(uf)))
+	}
+}
+
+// unknownFields : 인코딩할 보존 필드. names이면 문자열 키 필드만, 아니면 해시 키 필드만 반환
+func (fs *fields) unknownFields(strct reflect.Value, names bool) UnknownFields {
+	if fs.unknown == nil {
+		return nil
+	}
+	v, ok := fieldByIndex(strct, fs.unknown.index)
+	if !ok || v.Len() == 0 {
+		return nil
+	}
+
+	ufs := v.Interface().(UnknownFields)
+	for i, uf := range ufs {
+		if (uf.Width == FieldNameSizeFlagString) != names {
+			// 기록할 수 없는 필드가 있으면 걸러낸 사본을 사용
+			filtered := append(UnknownFields(nil), ufs[:i]...)
+			for _, uf := range ufs[i+1:] {
+				if (uf.Width == FieldNameSizeFlagString) == names {
+					filtered = append(filtered, uf)
+				}
+			}
+			return filtered
+		}
+	}
+	return ufs
+}
+
+// encodeUnknownFields : 보존 필드를 키와 함께 기록. fieldLen이 FieldNameSizeFlagMixed이면 필드별 크기를 사용
+func (e *Encoder) encodeUnknownFields(ufs UnknownFields, fieldLen FieldNameSizeFlag) error {
+	for _, uf := range ufs {
+		if uf.Width == FieldNameSizeFlagString {
+			if err := e.EncodeString(uf.Name); err != nil {
+				return err
+			}
+		} else {
+			width := fieldLen
+			if width == FieldNameSizeFlagMixed {
+				width = uf.Width
+			}
+			buf, err := FieldName{hash32: uf.Hash, size: uf.Width}.toBuffer(width)
+			if err != nil {
+				return err
+			}
+			if err := e.write(buf); err != nil {
+				return err
+			}
+		}
+		if len(uf.Value) == 0 {
+			if err := e.EncodeNil(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.write(uf.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package hpack_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type unknownV2 struct {
+	ID   int      `msgpack:"id"`
+	Name string   `msgpack:"name"`
+	F2   int      `msgpack:"f2"`
+	F50  []string `msgpack:"f50"`
+}
+
+type unknownV1 struct {
+	ID      int                 `msgpack:"id"`
+	Unknown hpack.UnknownFields `msgpack:",unknown"`
+}
+
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	in := unknownV2{ID: 1, Name: "kim", F2: 2, F50: []string{"a"}}
+	tests := []struct {
+		name  string
+		setup func(*hpack.Encoder)
+		width hpack.FieldNameSizeFlag
+	}{
+		{"hash keys", nil, 0},
+		{"field names", func(enc *hpack.Encoder) { enc.UseFieldNames(true) }, hpack.FieldNameSizeFlagString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := encode(t, &in, tt.setup)
+
+			var v1 unknownV1
+			if err := hpack.Unmarshal(b, &v1); err != nil {
+				t.Fatal(err)
+			}
+			if v1.ID != 1 || len(v1.Unknown) != 3 {
+				t.Fatalf("got %+v", v1)
+			}
+			for _, uf := range v1.Unknown {
+				if tt.width == hpack.FieldNameSizeFlagString && (uf.Width != tt.width || uf.Name == "") {
+					t.Fatalf("unknown field %+v", uf)
+				}
+			}
+
+			// 받은 형식(해시 키/문자열 키)으로 인코딩하면 모르는 필드도 다시 기록된다.
+			v1.ID = 3
+			var out unknownV2
+			if err := hpack.Unmarshal(encode(t, &v1, tt.setup), &out); err != nil {
+				t.Fatal(err)
+			}
+			want := in
+			want.ID = 3
+			if !reflect.DeepEqual(out, want) {
+				t.Fatalf("got %+v, want %+v", out, want)
+			}
+
+			// 다시 디코딩하면 이전에 모은 필드를 비운다.
+			if err := hpack.Unmarshal(encode(t, &unknownV1{ID: 4}, nil), &v1); err != nil || len(v1.Unknown) != 0 {
+				t.Fatalf("got %+v, err %v", v1, err)
+			}
+		})
+	}
+}
+
+func TestUnknownFieldsOtherFormat(t *testing.T) {
+	b := encode(t, &unknownV2{ID: 1, Name: "kim"}, func(enc *hpack.Encoder) { enc.UseFieldNames(true) })
+	var v1 unknownV1
+	if err := hpack.Unmarshal(b, &v1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 문자열 키로 받은 필드는 해시 키로 인코딩할 때 기록되지 않는다.
+	var out unknownV2
+	if err := hpack.Unmarshal(encode(t, &v1, nil), &out); err != nil || !reflect.DeepEqual(out, unknownV2{ID: 1}) {
+		t.Fatalf("got %+v, err %v", out, err)
+	}
+	if len(v1.Unknown) != 3 {
+		t.Fatalf("encoding changed Unknown: %+v", v1.Unknown)
+	}
+}
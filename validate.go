@@ -0,0 +1,169 @@
+package hpack
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaIssueKind : 타입 검증에서 발견된 문제 종류
+type SchemaIssueKind byte
+
+const (
+	IssueHashCollision SchemaIssueKind = iota + 1 // 4B에서도 해시가 충돌하여 직렬화에서 제외된 필드
+	IssueWidened                                  // 해시 충돌로 2B/4B 해시가 할당된 필드
+	IssueShadowed                                 // 같은 이름의 필드에 가려져 인라인되지 않은 embedded struct 필드
+	IssueUnsupported                              // 직렬화할 수 없는 타입(chan, func, complex 등)의 필드
+	IssueAliasConflict                            // 다른 필드명과 같거나 해시가 다른 필드(또는 alias)와 겹쳐 매핑되지 않은 alias
+	IssueInvalid                                  // 태그 오류(고정 해시 중복, ,unknown/intern 필드 타입 등). 인코딩/디코딩 시 에러
+)
+
+func (k SchemaIssueKind) ToString() string {
+	switch k {
+	case IssueHashCollision:
+		return "hash collision"
+	case IssueWidened:
+		return "widened hash"
+	case IssueShadowed:
+		return "shadowed field"
+	case IssueUnsupported:
+		return "unsupported type"
+	case IssueAliasConflict:
+		return "alias conflict"
+	case IssueInvalid:
+		return "invalid field"
+	}
+
+	return "Unknown"
+}
+
+// SchemaIssue : 타입 검증에서 발견된 문제 하나
+type SchemaIssue struct {
+	Kind   SchemaIssueKind
+	Type   reflect.Type      // 문제가 발견된 struct 타입
+	Field  string            // 필드명(태그명)
+	Size   FieldNameSizeFlag // IssueWidened: 할당된 해시 크기
+	GoType reflect.Type      // IssueUnsupported: 직렬화할 수 없는 타입
+	Err    error             // IssueInvalid: 태그 오류
+}
+
+func (issue SchemaIssue) String() string {
+	switch issue.Kind {
+	case IssueWidened:
+		return fmt.Sprintf("%s: %s.%s uses %s hash", issue.Kind.ToString(), issue.Type, issue.Field, issue.Size.ToString())
+	case IssueUnsupported:
+		return fmt.Sprintf("%s: %s.%s has type %s", issue.Kind.ToString(), issue.Type, issue.Field, issue.GoType)
+	case IssueAliasConflict:
+		if issue.Size != 0 {
+			return fmt.Sprintf("%s: alias %s.%s (%s hash)", issue.Kind.ToString(), issue.Type, issue.Field, issue.Size.ToString())
+		}
+		return fmt.Sprintf("%s: alias %s.%s is another field's name", issue.Kind.ToString(), issue.Type, issue.Field)
+	case IssueInvalid:
+		return fmt.Sprintf("%s: %s.%s: %v", issue.Kind.ToString(), issue.Type, issue.Field, issue.Err)
+	}
+	return fmt.Sprintf("%s: %s.%s", issue.Kind.ToString(), issue.Type, issue.Field)
+}
+
+// ValidationError : Validate가 반환하는 검증 결과
+type ValidationError struct {
+	Issues []SchemaIssue
+}
+
+func (err *ValidationError) Error() string {
+	ss := make([]string, 0, len(err.Issues))
+	for _, issue := range err.Issues {
+		ss = append(ss, issue.String())
+	}
+	return fmt.Sprintf("hpack: invalid schema (%d issues): %s", len(err.Issues), strings.Join(ss, "; "))
+}
+
+// Validate : typ에서 도달 가능한 모든 struct 타입을 검사
+//
+// 해시 충돌, 2B/4B로 늘어난 해시, 가려진 인라인 필드, 직렬화할 수 없는 필드를 찾으면
+// *ValidationError로 반환한다. 의도한 해시 크기는 태그(hash=, id=)로 고정하면 보고되지 않는다.
+func Validate(typ reflect.Type) error {
+	return ValidateWith(typ, SchemaOptions{})
+}
+
+// ValidateWith : Validate와 같지만 Encoder/Decoder와 같은 태그 키(SetStructTags), hasher(SetFieldHasher)를 기준으로 검사
+func ValidateWith(typ reflect.Type, opts SchemaOptions) error {
+	v := validator{tags: opts.tags(), hasher: opts.Hasher, visited: make(map[reflect.Type]struct{})}
+	v.walk(typ, nil, "")
+
+	if len(v.issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: v.issues}
+}
+
+// MustRegister : 값들의 타입을 Validate로 검사하고 문제가 있으면 panic
+// reflect.Type을 직접 전달할 수도 있다. 서비스 시작 시 스키마 검증 용도.
+func MustRegister(values ...interface{}) {
+	for _, value := range values {
+		typ, ok := value.(reflect.Type)
+		if !ok {
+			typ = reflect.TypeOf(value)
+		}
+		if err := Validate(typ); err != nil {
+			panic(err)
+		}
+	}
+}
+
+type validator struct {
+	tags    structTags
+	hasher  FieldHasher
+	visited map[reflect.Type]struct{}
+	issues  []SchemaIssue
+}
+
+func (v *validator) walk(typ, owner reflect.Type, field string) {
+	if typ == nil {
+		return
+	}
+
+	encoder := reflect.ValueOf(getEncoder(typ)).Pointer()
+	if encoder == encodeUnsupportedValuePtr {
+		v.issues = append(v.issues, SchemaIssue{Kind: IssueUnsupported, Type: owner, Field: field, GoType: typ})
+		return
+	}
+
+	if _, ok := v.visited[typ]; ok {
+		return
+	}
+	v.visited[typ] = struct{}{}
+
+	if encoder == encodeStructValuePtr {
+		fs := structs.load(typ, v.hasher, v.tags)
+		v.issues = append(v.issues, fs.issues...)
+		for _, f := range fs.List {
+			v.walk(f.typ, typ, f.fieldName.name)
+		}
+		return
+	}
+
+	// 커스텀 인코더(CustomEncoder, Marshaler 등)가 있는 타입은 내부를 검사하지 않음
+	if hasCustomEncoder(typ) {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		v.walk(typ.Elem(), owner, field)
+	case reflect.Map:
+		v.walk(typ.Key(), owner, field)
+		v.walk(typ.Elem(), owner, field)
+	}
+}
+
+func hasCustomEncoder(typ reflect.Type) bool {
+	for _, t := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+		if t.Implements(customEncoderType) ||
+			t.Implements(marshalerType) ||
+			t.Implements(binaryMarshalerType) ||
+			t.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	return false
+}
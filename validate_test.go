@@ -0,0 +1,180 @@
+package hpack_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/boldplaygames/hpack"
+)
+
+type vClean struct {
+	ID   int    `msgpack:"id"`
+	Name string `msgpack:"name"`
+}
+
+type vWidened struct {
+	F2  int `msgpack:"f2"`
+	F50 int `msgpack:"f50"` // f2와 1B 해시가 같음
+}
+
+type vPinnedWide struct {
+	F2  int `msgpack:"f2"`
+	F50 int `msgpack:"f50,hash=0x1234"`
+}
+
+type vShadowInner struct {
+	A int `msgpack:"a"`
+	B int `msgpack:"b"`
+}
+
+type vShadow struct {
+	vShadowInner `msgpack:",inline"`
+	A            string `msgpack:"a"`
+}
+
+type vUnsupported struct {
+	C chan int `msgpack:"c"`
+}
+
+type vNested struct {
+	Items []*vWidened       `msgpack:"items"`
+	ByID  map[int]vClean    `msgpack:"byId"`
+	Any   interface{}       `msgpack:"any"`
+	Fn    map[string]func() `msgpack:"fn"`
+}
+
+type vDupPinned struct {
+	A int `msgpack:"a,hash=1"`
+	B int `msgpack:"b,id=1"`
+}
+
+type vTwoUnknown struct {
+	A  int                 `msgpack:"a"`
+	U1 hpack.UnknownFields `msgpack:",unknown"`
+	U2 hpack.UnknownFields `msgpack:",unknown"`
+}
+
+type vBadUnknown struct {
+	U map[uint32][]byte `msgpack:",unknown"`
+}
+
+type vBadIntern struct {
+	N int `msgpack:"n,intern"`
+}
+
+type vBadHash struct {
+	A int `msgpack:"a,hash=zz"`
+}
+
+type vBadInline struct {
+	vBadIntern `msgpack:",inline"`
+	B          int `msgpack:"b"`
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     interface{}
+		kinds []hpack.SchemaIssueKind
+	}{
+		{"clean", vClean{}, nil},
+		{"widened", vWidened{}, []hpack.SchemaIssueKind{hpack.IssueWidened, hpack.IssueWidened}},
+		{"pinned", vPinnedWide{}, nil},
+		{"shadowed", vShadow{}, []hpack.SchemaIssueKind{hpack.IssueShadowed}},
+		{"unsupported", vUnsupported{}, []hpack.SchemaIssueKind{hpack.IssueUnsupported}},
+		{"nested", &vNested{}, []hpack.SchemaIssueKind{hpack.IssueWidened, hpack.IssueWidened, hpack.IssueUnsupported}},
+		{"duplicate pinned hash", vDupPinned{}, []hpack.SchemaIssueKind{hpack.IssueInvalid}},
+		{"two unknown fields", vTwoUnknown{}, []hpack.SchemaIssueKind{hpack.IssueInvalid}},
+		{"bad unknown type", vBadUnknown{}, []hpack.SchemaIssueKind{hpack.IssueInvalid}},
+		{"bad intern type", vBadIntern{}, []hpack.SchemaIssueKind{hpack.IssueInvalid}},
+		{"bad hash", vBadHash{}, []hpack.SchemaIssueKind{hpack.IssueInvalid}},
+		{"bad inlined field", vBadInline{}, []hpack.SchemaIssueKind{hpack.IssueInvalid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validationIssues(t, tt.v)
+			kinds := make([]hpack.SchemaIssueKind, 0, len(issues))
+			for _, issue := range issues {
+				kinds = append(kinds, issue.Kind)
+				if issue.String() == "" {
+					t.Fatal("empty issue string")
+				}
+			}
+			if len(kinds) != len(tt.kinds) || (len(kinds) > 0 && !reflect.DeepEqual(kinds, tt.kinds)) {
+				t.Fatalf("issues = %v, want kinds %v", issues, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidErrorsOnCodec(t *testing.T) {
+	for _, v := range []interface{}{&vDupPinned{}, &vTwoUnknown{}, &vBadUnknown{}, &vBadIntern{}, &vBadHash{}} {
+		if _, err := hpack.Marshal(v); err == nil {
+			t.Errorf("%T: Marshal returned no error", v)
+		}
+		if err := hpack.Unmarshal([]byte{hpack.FixedMapLow, 0}, v); err == nil {
+			t.Errorf("%T: Unmarshal returned no error", v)
+		}
+		if err := hpack.Unmarshal([]byte{hpack.FixedArrayLow | 1, 0}, v); err == nil {
+			t.Errorf("%T: Unmarshal(array) returned no error", v)
+		}
+
+		if _, err := hpack.SchemaOf(reflect.TypeOf(v)); err == nil {
+			t.Errorf("%T: SchemaOf returned no error", v)
+		}
+	}
+}
+
+type vOmitInvalid struct {
+	Inner vBadHash `msgpack:"inner,omitempty"`
+}
+
+func TestValidateInvalidOmitEmpty(t *testing.T) {
+	// omitempty 검사에서도 panic 없이 struct를 인코딩할 때 태그 오류를 반환한다.
+	if _, err := hpack.Marshal(&vOmitInvalid{}); err == nil {
+		t.Fatal("Marshal returned no error")
+	}
+}
+
+func TestMustRegister(t *testing.T) {
+	hpack.MustRegister(vClean{}, reflect.TypeOf(&vPinnedWide{}))
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		var verr *hpack.ValidationError
+		if !ok || !errors.As(err, &verr) || len(verr.Issues) != 1 || verr.Issues[0].Kind != hpack.IssueInvalid {
+			t.Fatalf("recovered %v, want *ValidationError with one invalid field", r)
+		}
+	}()
+	hpack.MustRegister(vClean{}, vDupPinned{})
+}
+
+type vTagged struct {
+	A  int `hpack:"f2" json:"a"`
+	B  int `json:"f50"`
+	C  int `msgpack:"c"`
+	Ch chan int
+}
+
+func TestValidateWithTags(t *testing.T) {
+	// msgpack 태그가 없으므로 기본 설정에서는 Go 필드명(A, B, C, Ch)을 읽는다.
+	if err := hpack.Validate(reflect.TypeOf(vTagged{})); err == nil {
+		t.Fatal("Validate: want unsupported Ch")
+	}
+
+	err := hpack.ValidateWith(reflect.TypeOf(vTagged{}), hpack.SchemaOptions{PrimaryTag: "hpack", FallbackTag: "json"})
+	var verr *hpack.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want *ValidationError", err)
+	}
+	fields := make(map[string]hpack.SchemaIssueKind)
+	for _, issue := range verr.Issues {
+		fields[issue.Field] = issue.Kind
+	}
+	want := map[string]hpack.SchemaIssueKind{"f2": hpack.IssueWidened, "f50": hpack.IssueWidened, "Ch": hpack.IssueUnsupported}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("issues = %v, want %v", verr.Issues, want)
+	}
+}